@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// UploadAttachmentReq is the request for uploading a file to be sent to a chat room
+type UploadAttachmentReq struct {
+	g.Meta `path:"/chat/attachment/upload/{roomId}" method:"post" mime:"multipart/form-data" tags:"Chat" summary:"Upload an attachment" auth:"true"`
+	RoomId uint              `v:"required|min:1" in:"path" dc:"Room the file will be sent to"`
+	File   *ghttp.UploadFile `v:"required" type:"file" dc:"File to upload"`
+}
+
+// UploadAttachmentRes is the response for uploading an attachment
+type UploadAttachmentRes struct {
+	Attachment *Attachment `json:"attachment" dc:"Uploaded attachment; send a message with its ID to share it"`
+}
+
+// DownloadAttachmentReq is the request for downloading the contents of an attachment
+type DownloadAttachmentReq struct {
+	g.Meta `path:"/chat/attachment/{id}" method:"get" tags:"Chat" summary:"Download an attachment" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Attachment ID"`
+}
+
+// DownloadAttachmentRes is the response for downloading an attachment; the contents are written directly
+type DownloadAttachmentRes struct{}
+
+// DownloadThumbnailReq is the request for downloading a thumbnail of an image attachment
+type DownloadThumbnailReq struct {
+	g.Meta `path:"/chat/attachment/{id}/thumbnail/{size}" method:"get" tags:"Chat" summary:"Download an image thumbnail" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Attachment ID"`
+	Size   int  `v:"required|min:1" in:"path" dc:"Edge of the thumbnail bounding box in pixels"`
+}
+
+// DownloadThumbnailRes is the response for downloading a thumbnail; the contents are written directly
+type DownloadThumbnailRes struct{}
+
+// Attachment is the metadata of an uploaded file
+type Attachment struct {
+	Id         uint        `json:"id"                   dc:"Attachment ID"`
+	Name       string      `json:"name"                 dc:"Original file name"`
+	Size       int64       `json:"size"                 dc:"Size in bytes"`
+	MimeType   string      `json:"mimeType"             dc:"MIME type detected from the contents"`
+	Checksum   string      `json:"checksum"             dc:"Hex-encoded SHA-256 of the contents"`
+	Url        string      `json:"url"                  dc:"Download URL"`
+	Width      int         `json:"width,omitempty"      dc:"Image width in pixels"`
+	Height     int         `json:"height,omitempty"     dc:"Image height in pixels"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty" dc:"Thumbnails of images larger than the thumbnail sizes, smallest first"`
+}
+
+// Thumbnail is a scaled down copy of an image attachment
+type Thumbnail struct {
+	Size   int    `json:"size"   dc:"Edge of the bounding box in pixels"`
+	Width  int    `json:"width"  dc:"Width in pixels"`
+	Height int    `json:"height" dc:"Height in pixels"`
+	Url    string `json:"url"    dc:"Download URL"`
+}
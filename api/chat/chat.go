@@ -7,27 +7,39 @@ import (
 // ConnectReq is the request for connecting to WebSocket chat
 type ConnectReq struct {
 	g.Meta `path:"/ws/chat" method:"get" tags:"Chat" summary:"Connect to WebSocket chat" auth:"true"`
-	RoomId uint `v:"required|min:1" dc:"Room ID to join"`
+	RoomId uint `v:"min:0" dc:"Room to subscribe to right away and to send frames without a room ID to; other rooms are subscribed to with subscribe frames"`
+	Seq    uint `v:"min:0" dc:"Sequence number of the last message seen in the room; the messages after it are replayed"`
 }
 
 // MessageReq represents a message sent from the client
 type MessageReq struct {
-	Type    int    `json:"type"    description:"Message type: 0-text, 1-image, 2-file"`
-	Content string `json:"content" description:"Message content"`
-	RoomId  uint   `json:"roomId"  description:"Room ID"`
+	Type         int    `json:"type"         description:"Message type: 0-text, 1-image, 2-file"`
+	Content      string `json:"content"      description:"Message content; the attachment name for image and file messages"`
+	RoomId       uint   `json:"roomId"       description:"Room ID"`
+	ParentId     uint   `json:"parentId"     description:"Message to reply to; replies join the thread of its root message"`
+	AttachmentId uint   `json:"attachmentId" description:"Uploaded attachment of image and file messages"`
+	Nonce        string `json:"nonce"        description:"Optional client key of the send; sending again with the same key returns the original message"`
 }
 
 // MessageRes represents a message sent to the client
 type MessageRes struct {
-	Id        uint   `json:"id"        description:"Message ID"`
-	Type      int    `json:"type"      description:"Message type: 0-text, 1-image, 2-file, 3-system"`
-	Content   string `json:"content"   description:"Message content"`
-	RoomId    uint   `json:"roomId"    description:"Room ID"`
-	UserId    uint   `json:"userId"    description:"User ID who sent the message"`
-	Username  string `json:"username"  description:"Username who sent the message"`
-	Nickname  string `json:"nickname"  description:"Nickname who sent the message"`
-	Avatar    string `json:"avatar"    description:"Avatar URL of the sender"`
-	Timestamp string `json:"timestamp" description:"Message timestamp"`
+	Id          uint        `json:"id"          description:"Message ID"`
+	Type        int         `json:"type"        description:"Message type: 0-text, 1-image, 2-file, 3-system"`
+	Content     string      `json:"content"     description:"Message content"`
+	RoomId      uint        `json:"roomId"      description:"Room ID"`
+	UserId      uint        `json:"userId"      description:"User ID who sent the message"`
+	Username    string      `json:"username"    description:"Username who sent the message"`
+	Nickname    string      `json:"nickname"    description:"Nickname who sent the message"`
+	Avatar      string      `json:"avatar"      description:"Avatar URL of the sender"`
+	Timestamp   string      `json:"timestamp"   description:"Message timestamp"`
+	EditedAt    string      `json:"editedAt"    description:"Last edited time, empty if never edited"`
+	Deleted     bool        `json:"deleted"     description:"Whether the message was deleted; deleted messages have no content"`
+	Reactions   []Reaction  `json:"reactions"   description:"Aggregated emoji reactions"`
+	ParentId    uint        `json:"parentId"    description:"Root message of the thread, 0 for messages in the main timeline"`
+	Seq         uint        `json:"seq"         description:"Sequence number of the message in its room"`
+	ReplyCount  int         `json:"replyCount"  description:"Number of replies to a thread root"`
+	LastReplyAt string      `json:"lastReplyAt" description:"Time of the last reply to a thread root, empty if none"`
+	Attachment  *Attachment `json:"attachment"  description:"Attachment of image and file messages"`
 }
 
 // RoomMembersReq is the request for getting room members
@@ -49,20 +61,41 @@ type Member struct {
 	Nickname string `json:"nickname" dc:"Nickname"`
 	Avatar   string `json:"avatar"   dc:"Avatar URL"`
 	Status   int    `json:"status"   dc:"User status: 0-offline, 1-online"`
+	Role     string `json:"role"     dc:"Room role: owner, moderator, member"`
 }
 
-// HistoryReq is the request for getting chat history
+// HistoryReq is the request for getting chat history. At most one of Before, After and Around
+// may be set; without a cursor the latest messages are returned.
 type HistoryReq struct {
 	g.Meta `path:"/chat/history/{roomId}" method:"get" tags:"Chat" summary:"Get chat history" auth:"true"`
 	RoomId uint `v:"required|min:1" in:"path" dc:"Room ID"`
-	Page   int  `d:"1"  v:"min:1"    dc:"Page number, starting from 1"`
-	Size   int  `d:"20" v:"max:100"  dc:"Page size, maximum 100"`
+	Before uint `dc:"Return messages older than this message ID"`
+	After  uint `dc:"Return messages newer than this message ID"`
+	Around uint `dc:"Return messages around this message ID, including it"`
+	Size   int  `d:"20" v:"min:1|max:100" dc:"Number of messages, maximum 100"`
 }
 
 // HistoryRes is the response for chat history
 type HistoryRes struct {
-	Messages []MessageRes `json:"messages" dc:"List of messages"`
-	Total    int          `json:"total"    dc:"Total number of messages"`
-	Page     int          `json:"page"     dc:"Current page number"`
-	Size     int          `json:"size"     dc:"Page size"`
+	Messages   []MessageRes `json:"messages"   dc:"List of messages, newest first"`
+	PrevCursor uint         `json:"prevCursor" dc:"Pass as before to load older messages, 0 if there are none"`
+	NextCursor uint         `json:"nextCursor" dc:"Pass as after to load newer messages, 0 if there are none"`
+	Size       int          `json:"size"       dc:"Requested number of messages"`
+}
+
+// ThreadReq is the request for getting a thread
+type ThreadReq struct {
+	g.Meta `path:"/chat/thread/{id}" method:"get" tags:"Chat" summary:"Get a thread" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Root message ID"`
+	Page   int  `d:"1"  v:"min:1"         dc:"Page number of replies, starting from 1"`
+	Size   int  `d:"50" v:"min:1|max:100" dc:"Page size of replies, maximum 100"`
+}
+
+// ThreadRes is the response for a thread
+type ThreadRes struct {
+	Root    MessageRes   `json:"root"    dc:"Root message of the thread"`
+	Replies []MessageRes `json:"replies" dc:"Replies, oldest first"`
+	Total   int          `json:"total"   dc:"Total number of replies"`
+	Page    int          `json:"page"    dc:"Current page number"`
+	Size    int          `json:"size"    dc:"Page size"`
 }
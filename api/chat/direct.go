@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// DirectSendReq is the request for sending a direct message to a user
+type DirectSendReq struct {
+	g.Meta       `path:"/chat/direct/send/{userId}" method:"post" tags:"Chat" summary:"Send a direct message" auth:"true"`
+	UserId       uint   `v:"required|min:1" in:"path" dc:"Recipient user ID"`
+	Type         int    `d:"0" v:"in:0,1,2" dc:"Message type: 0-text, 1-image, 2-file"`
+	Content      string `v:"required-if:Type,0" dc:"Content of text messages"`
+	AttachmentId uint   `dc:"Attachment of image and file messages, uploaded to the direct conversation"`
+	Nonce        string `v:"max-length:64" dc:"Optional client key of the send; sending again with the same key returns the original message"`
+}
+
+// DirectSendRes is the response for sending a direct message
+type DirectSendRes struct {
+	RoomId    uint `json:"roomId"    dc:"ID of the direct conversation, usable with /chat/history and /ws/chat"`
+	MessageId uint `json:"messageId" dc:"ID of the stored message"`
+}
+
+// DirectListReq is the request for listing the caller's direct conversations
+type DirectListReq struct {
+	g.Meta `path:"/chat/direct/list" method:"get" tags:"Chat" summary:"List direct conversations" auth:"true"`
+}
+
+// DirectListRes is the response for listing direct conversations
+type DirectListRes struct {
+	List []DirectConversation `json:"list" dc:"Direct conversations, most recently active first"`
+}
+
+// DirectConversation is a one-to-one conversation with another user
+type DirectConversation struct {
+	RoomId      uint        `json:"roomId"      dc:"ID of the direct conversation"`
+	Peer        Member      `json:"peer"        dc:"The other user of the conversation"`
+	LastMessage *MessageRes `json:"lastMessage" dc:"Most recent message, null if there is none"`
+}
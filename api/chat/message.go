@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// SendMessageReq is the request for sending a message to a room without a WebSocket connection
+type SendMessageReq struct {
+	g.Meta       `path:"/chat/message" method:"post" tags:"Chat" summary:"Send a message" auth:"true"`
+	RoomId       uint   `v:"required|min:1" dc:"Room ID"`
+	Type         int    `d:"0" v:"in:0,1,2" dc:"Message type: 0-text, 1-image, 2-file"`
+	Content      string `v:"required-if:Type,0|max-length:5000" dc:"Content of text messages"`
+	ParentId     uint   `dc:"Message to reply to; replies join the thread of its root message"`
+	AttachmentId uint   `dc:"Attachment of image and file messages, uploaded to the room"`
+	Nonce        string `v:"max-length:64" dc:"Optional client key of the send; sending again with the same key returns the original message"`
+}
+
+// SendMessageRes is the response for sending a message
+type SendMessageRes struct {
+	MessageId uint `json:"messageId" dc:"ID of the stored message"`
+	Seq       uint `json:"seq"       dc:"Sequence number of the message in its room"`
+}
+
+// EditMessageReq is the request for editing a sent message
+type EditMessageReq struct {
+	g.Meta  `path:"/chat/message/edit/{id}" method:"post" tags:"Chat" summary:"Edit a sent message" auth:"true"`
+	Id      uint   `v:"required|min:1" in:"path" dc:"Message ID"`
+	Content string `v:"required|max-length:5000" dc:"New message content"`
+}
+
+// EditMessageRes is the response for editing a message
+type EditMessageRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// DeleteMessageReq is the request for deleting (recalling) a message
+type DeleteMessageReq struct {
+	g.Meta `path:"/chat/message/delete/{id}" method:"post" tags:"Chat" summary:"Delete a message" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Message ID"`
+}
+
+// DeleteMessageRes is the response for deleting a message
+type DeleteMessageRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// MessageEditsReq is the request for getting the edit history of a message
+type MessageEditsReq struct {
+	g.Meta `path:"/chat/message/edits/{id}" method:"get" tags:"Chat" summary:"Get the edit history of a message" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Message ID"`
+}
+
+// MessageEditsRes is the response for the edit history of a message
+type MessageEditsRes struct {
+	List []MessageEdit `json:"list" dc:"Previous contents, oldest first"`
+}
+
+// MessageEdit is a previous content of an edited message
+type MessageEdit struct {
+	Content  string `json:"content"  dc:"Content before the edit"`
+	EditedAt string `json:"editedAt" dc:"Time of the edit"`
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// NotificationListReq is the request for listing the caller's notifications
+type NotificationListReq struct {
+	g.Meta `path:"/chat/notification/list" method:"get" tags:"Chat" summary:"List notifications" auth:"true"`
+	Unread bool `dc:"Only list unread notifications"`
+	Page   int  `d:"1"  v:"min:1"   dc:"Page number, starting from 1"`
+	Size   int  `d:"20" v:"max:100" dc:"Page size, maximum 100"`
+}
+
+// NotificationListRes is the response for listing notifications
+type NotificationListRes struct {
+	List   []Notification `json:"list"   dc:"Notifications, newest first"`
+	Total  int            `json:"total"  dc:"Total number of listed notifications"`
+	Unread int            `json:"unread" dc:"Number of unread notifications"`
+	Page   int            `json:"page"   dc:"Current page number"`
+	Size   int            `json:"size"   dc:"Page size"`
+}
+
+// Notification is an entry of a user's notification inbox
+type Notification struct {
+	Id            uint   `json:"id"            dc:"Notification ID"`
+	Type          string `json:"type"          dc:"Notification type: mention, here or all"`
+	RoomId        uint   `json:"roomId"        dc:"Room the notification comes from"`
+	MessageId     uint   `json:"messageId"     dc:"Message that mentioned the user"`
+	ActorId       uint   `json:"actorId"       dc:"User who sent the message"`
+	ActorNickname string `json:"actorNickname" dc:"Nickname of the user who sent the message"`
+	Content       string `json:"content"       dc:"Excerpt of the message"`
+	IsRead        bool   `json:"isRead"        dc:"Whether the notification has been dismissed"`
+	CreatedAt     string `json:"createdAt"     dc:"Created time"`
+}
+
+// DismissNotificationReq is the request for dismissing a notification
+type DismissNotificationReq struct {
+	g.Meta `path:"/chat/notification/dismiss/{id}" method:"post" tags:"Chat" summary:"Dismiss a notification" auth:"true"`
+	Id     uint `v:"required|min:1" in:"path" dc:"Notification ID"`
+}
+
+// DismissNotificationRes is the response for dismissing a notification
+type DismissNotificationRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// DismissAllNotificationsReq is the request for dismissing all notifications of the caller
+type DismissAllNotificationsReq struct {
+	g.Meta `path:"/chat/notification/dismiss-all" method:"post" tags:"Chat" summary:"Dismiss all notifications" auth:"true"`
+}
+
+// DismissAllNotificationsRes is the response for dismissing all notifications
+type DismissAllNotificationsRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
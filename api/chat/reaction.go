@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ReactReq is the request for adding an emoji reaction to a message
+type ReactReq struct {
+	g.Meta `path:"/chat/message/react/{id}" method:"post" tags:"Chat" summary:"React to a message" auth:"true"`
+	Id     uint   `v:"required|min:1" in:"path" dc:"Message ID"`
+	Emoji  string `v:"required|max-length:32" dc:"Reaction emoji"`
+}
+
+// ReactRes is the response for adding a reaction
+type ReactRes struct {
+	Reactions []Reaction `json:"reactions" dc:"Aggregated reactions of the message"`
+}
+
+// UnreactReq is the request for removing an emoji reaction from a message
+type UnreactReq struct {
+	g.Meta `path:"/chat/message/unreact/{id}" method:"post" tags:"Chat" summary:"Remove a reaction from a message" auth:"true"`
+	Id     uint   `v:"required|min:1" in:"path" dc:"Message ID"`
+	Emoji  string `v:"required|max-length:32" dc:"Reaction emoji"`
+}
+
+// UnreactRes is the response for removing a reaction
+type UnreactRes struct {
+	Reactions []Reaction `json:"reactions" dc:"Aggregated reactions of the message"`
+}
+
+// Reaction is the number of users who reacted to a message with an emoji
+type Reaction struct {
+	Emoji   string `json:"emoji"   dc:"Reaction emoji"`
+	Count   int    `json:"count"   dc:"Number of users who reacted"`
+	Reacted bool   `json:"reacted" dc:"Whether the current user reacted with this emoji"`
+}
@@ -0,0 +1,37 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// UnreadReq is the request for getting unread message counts of the caller's rooms
+type UnreadReq struct {
+	g.Meta `path:"/chat/unread" method:"get" tags:"Chat" summary:"Get unread message counts" auth:"true"`
+}
+
+// UnreadRes is the response for unread message counts
+type UnreadRes struct {
+	List  []RoomUnread `json:"list"  dc:"Unread counts of every room the user is in"`
+	Total int          `json:"total" dc:"Total number of unread messages"`
+}
+
+// RoomUnread is the unread message count of a room
+type RoomUnread struct {
+	RoomId            uint   `json:"roomId"            dc:"Room ID"`
+	Name              string `json:"name"              dc:"Room name, empty for direct conversations"`
+	Type              string `json:"type"              dc:"Room type: group or direct"`
+	Unread            int    `json:"unread"            dc:"Number of unread messages"`
+	LastReadMessageId uint   `json:"lastReadMessageId" dc:"ID of the last read message"`
+}
+
+// MarkReadReq is the request for marking the messages of a room read
+type MarkReadReq struct {
+	g.Meta    `path:"/chat/read/{roomId}" method:"post" tags:"Chat" summary:"Mark messages read" auth:"true"`
+	RoomId    uint `v:"required|min:1" in:"path" dc:"Room ID"`
+	MessageId uint `v:"required|min:1" dc:"ID of the last read message; earlier messages are marked read too"`
+}
+
+// MarkReadRes is the response for marking messages read
+type MarkReadRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
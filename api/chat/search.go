@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// SearchReq is the request for searching the messages of the caller's rooms
+type SearchReq struct {
+	g.Meta `path:"/chat/search" method:"get" tags:"Chat" summary:"Search messages" auth:"true"`
+	Query  string `v:"required|length:3,100" dc:"Text to search for (3-100 chars)"`
+	RoomId uint   `dc:"Only search this room"`
+	UserId uint   `dc:"Only search messages of this sender"`
+	From   string `v:"date-format:Y-m-d" dc:"Only search messages sent on or after this date (YYYY-MM-DD)"`
+	To     string `v:"date-format:Y-m-d" dc:"Only search messages sent on or before this date (YYYY-MM-DD)"`
+	Page   int    `d:"1"  v:"min:1"   dc:"Page number, starting from 1"`
+	Size   int    `d:"20" v:"max:50"  dc:"Page size, maximum 50"`
+}
+
+// SearchRes is the response for searching messages
+type SearchRes struct {
+	List  []SearchResult `json:"list"  dc:"Matching messages, best match first"`
+	Total int            `json:"total" dc:"Total number of matching messages"`
+	Page  int            `json:"page"  dc:"Current page number"`
+	Size  int            `json:"size"  dc:"Page size"`
+}
+
+// SearchResult is a message matching a search
+type SearchResult struct {
+	Message  MessageRes `json:"message"  dc:"Matching message"`
+	RoomName string     `json:"roomName" dc:"Name of the message's room, empty for direct conversations"`
+	Snippet  string     `json:"snippet"  dc:"Excerpt of the message with the matches wrapped in <mark> tags, HTML-escaped"`
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// StorageUsageReq is the request for the caller's attachment storage usage
+type StorageUsageReq struct {
+	g.Meta `path:"/chat/storage/usage" method:"get" tags:"Chat" summary:"Get my storage usage" auth:"true"`
+}
+
+// StorageUsageRes is the response for the caller's storage usage
+type StorageUsageRes struct {
+	Usage *StorageUsage `json:"usage" dc:"Storage usage of the attachments the caller uploaded"`
+}
+
+// RoomStorageUsageReq is the request for the attachment storage usage of a room
+type RoomStorageUsageReq struct {
+	g.Meta `path:"/chat/storage/room/{roomId}" method:"get" tags:"Chat" summary:"Get the storage usage of a room" auth:"true"`
+	RoomId uint `v:"required|min:1" in:"path" dc:"Room ID"`
+}
+
+// RoomStorageUsageRes is the response for the storage usage of a room
+type RoomStorageUsageRes struct {
+	Usage *StorageUsage `json:"usage" dc:"Storage usage of the attachments uploaded to the room"`
+}
+
+// StorageReportReq is the request for the storage report of all users and rooms
+type StorageReportReq struct {
+	g.Meta `path:"/chat/storage/report" method:"get" tags:"Chat" summary:"Get the storage report" auth:"true"`
+	Limit  int `d:"10" v:"min:1|max:100" dc:"Number of top users and rooms, maximum 100"`
+}
+
+// StorageReportRes is the response for the storage report
+type StorageReportRes struct {
+	Total *StorageUsage     `json:"total" dc:"Storage usage of all attachments and unfinished uploads; quota is not set"`
+	Users []StorageConsumer `json:"users" dc:"Users whose attachments take the most space, largest first"`
+	Rooms []StorageConsumer `json:"rooms" dc:"Rooms whose attachments take the most space, largest first"`
+}
+
+// StorageUsage is the space taken by stored attachments against a quota
+type StorageUsage struct {
+	Files   int   `json:"files"   dc:"Number of stored attachments"`
+	Bytes   int64 `json:"bytes"   dc:"Total size of the stored attachments in bytes"`
+	Pending int64 `json:"pending" dc:"Bytes reserved by unfinished resumable uploads"`
+	Quota   int64 `json:"quota"   dc:"Quota in bytes covering stored and pending bytes, 0 if unlimited"`
+}
+
+// StorageConsumer is a user or room in the storage report
+type StorageConsumer struct {
+	Id    uint   `json:"id"    dc:"User or room ID"`
+	Name  string `json:"name"  dc:"Username or room name"`
+	Files int    `json:"files" dc:"Number of stored attachments"`
+	Bytes int64  `json:"bytes" dc:"Total size of the stored attachments in bytes"`
+	Quota int64  `json:"quota" dc:"Quota in bytes, 0 if unlimited"`
+}
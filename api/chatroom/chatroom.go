@@ -65,7 +65,8 @@ type DetailRes struct {
 // JoinReq is the request for joining a chat room
 type JoinReq struct {
 	g.Meta `path:"/chatroom/join/{id}" method:"post" tags:"ChatRoom" summary:"Join a chat room" auth:"true"`
-	Id     uint `v:"required|min:1" dc:"Room ID"`
+	Id     uint   `v:"required|min:1" dc:"Room ID"`
+	Code   string `dc:"Invite link code, required for private rooms without an invitation"`
 }
 
 // JoinRes is the response for joining a chat room
@@ -94,3 +95,16 @@ type DeleteReq struct {
 type DeleteRes struct {
 	Success bool `json:"success" dc:"Whether the operation was successful"`
 }
+
+// SetRoleReq is the request for promoting or demoting a room member
+type SetRoleReq struct {
+	g.Meta `path:"/chatroom/role/{id}" method:"post" tags:"ChatRoom" summary:"Set a member's room role" auth:"true"`
+	Id     uint   `v:"required|min:1" dc:"Room ID"`
+	UserId uint   `v:"required|min:1" dc:"ID of the member whose role is changed"`
+	Role   string `v:"required|in:moderator,member" dc:"New room role: moderator or member"`
+}
+
+// SetRoleRes is the response for setting a member's room role
+type SetRoleRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
@@ -0,0 +1,103 @@
+package chatroom
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// InviteReq is the request for inviting a user to a chat room
+type InviteReq struct {
+	g.Meta   `path:"/chatroom/invite/{id}" method:"post" tags:"ChatRoom" summary:"Invite a user to a chat room" auth:"true"`
+	Id       uint   `v:"required|min:1" dc:"Room ID"`
+	Username string `v:"required" dc:"Username of the user to invite"`
+}
+
+// InviteRes is the response for inviting a user to a chat room
+type InviteRes struct {
+	InvitationId uint `json:"invitationId" dc:"Invitation ID"`
+}
+
+// InvitationListReq is the request for listing the caller's pending invitations
+type InvitationListReq struct {
+	g.Meta `path:"/chatroom/invitations" method:"get" tags:"ChatRoom" summary:"List pending invitations" auth:"true"`
+}
+
+// InvitationListRes is the response for listing pending invitations
+type InvitationListRes struct {
+	List []Invitation `json:"list" dc:"List of pending invitations"`
+}
+
+// Invitation is a pending invitation to a chat room
+type Invitation struct {
+	Id              uint   `json:"id" dc:"Invitation ID"`
+	RoomId          uint   `json:"roomId" dc:"Room ID"`
+	RoomName        string `json:"roomName" dc:"Room name"`
+	InviterId       uint   `json:"inviterId" dc:"ID of the user who sent the invitation"`
+	InviterNickname string `json:"inviterNickname" dc:"Nickname of the user who sent the invitation"`
+	CreatedAt       string `json:"createdAt" dc:"Invitation time"`
+}
+
+// AcceptInvitationReq is the request for accepting an invitation
+type AcceptInvitationReq struct {
+	g.Meta `path:"/chatroom/invitation/accept/{id}" method:"post" tags:"ChatRoom" summary:"Accept an invitation" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Invitation ID"`
+}
+
+// AcceptInvitationRes is the response for accepting an invitation
+type AcceptInvitationRes struct {
+	RoomId uint `json:"roomId" dc:"ID of the joined room"`
+}
+
+// DeclineInvitationReq is the request for declining an invitation
+type DeclineInvitationReq struct {
+	g.Meta `path:"/chatroom/invitation/decline/{id}" method:"post" tags:"ChatRoom" summary:"Decline an invitation" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Invitation ID"`
+}
+
+// DeclineInvitationRes is the response for declining an invitation
+type DeclineInvitationRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// CreateInviteLinkReq is the request for creating a shareable invite link
+type CreateInviteLinkReq struct {
+	g.Meta    `path:"/chatroom/invite-link/{id}" method:"post" tags:"ChatRoom" summary:"Create an invite link" auth:"true"`
+	Id        uint `v:"required|min:1" dc:"Room ID"`
+	ExpiresIn int  `d:"86400" v:"min:0" dc:"Seconds until the link expires, 0 for never"`
+	MaxUses   int  `d:"0" v:"min:0" dc:"Maximum number of joins, 0 for unlimited"`
+}
+
+// CreateInviteLinkRes is the response for creating an invite link
+type CreateInviteLinkRes struct {
+	InviteLink
+}
+
+// InviteLinkListReq is the request for listing the invite links of a room
+type InviteLinkListReq struct {
+	g.Meta `path:"/chatroom/invite-links/{id}" method:"get" tags:"ChatRoom" summary:"List invite links" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Room ID"`
+}
+
+// InviteLinkListRes is the response for listing invite links
+type InviteLinkListRes struct {
+	List []InviteLink `json:"list" dc:"List of active invite links"`
+}
+
+// RevokeInviteLinkReq is the request for revoking an invite link
+type RevokeInviteLinkReq struct {
+	g.Meta `path:"/chatroom/invite-link/revoke/{code}" method:"post" tags:"ChatRoom" summary:"Revoke an invite link" auth:"true"`
+	Code   string `v:"required" dc:"Invite link code"`
+}
+
+// RevokeInviteLinkRes is the response for revoking an invite link
+type RevokeInviteLinkRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// InviteLink is a shareable invite link, joined with JoinReq.Code
+type InviteLink struct {
+	Code      string `json:"code" dc:"Invite code to pass when joining the room"`
+	RoomId    uint   `json:"roomId" dc:"Room ID"`
+	MaxUses   int    `json:"maxUses" dc:"Maximum number of joins, 0 for unlimited"`
+	Uses      int    `json:"uses" dc:"Number of joins so far"`
+	ExpiresAt string `json:"expiresAt" dc:"Expiration time, empty for never"`
+}
@@ -0,0 +1,119 @@
+package chatroom
+
+import (
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// KickReq is the request for kicking a member out of a chat room
+type KickReq struct {
+	g.Meta `path:"/chatroom/kick/{id}" method:"post" tags:"ChatRoom" summary:"Kick a member out of a chat room" auth:"true"`
+	Id     uint   `v:"required|min:1" dc:"Room ID"`
+	UserId uint   `v:"required|min:1" dc:"ID of the member to kick"`
+	Reason string `v:"max-length:200" dc:"Reason shown to the room (max 200 chars)"`
+}
+
+// KickRes is the response for kicking a member
+type KickRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// BanReq is the request for banning a user from a chat room
+type BanReq struct {
+	g.Meta   `path:"/chatroom/ban/{id}" method:"post" tags:"ChatRoom" summary:"Ban a user from a chat room" auth:"true"`
+	Id       uint   `v:"required|min:1" dc:"Room ID"`
+	UserId   uint   `v:"required|min:1" dc:"ID of the user to ban"`
+	Duration int    `v:"min:0" dc:"Ban duration in seconds, 0 for a permanent ban"`
+	Reason   string `v:"max-length:200" dc:"Reason shown to the room (max 200 chars)"`
+}
+
+// BanRes is the response for banning a user
+type BanRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// UnbanReq is the request for lifting a ban
+type UnbanReq struct {
+	g.Meta `path:"/chatroom/unban/{id}" method:"post" tags:"ChatRoom" summary:"Lift a ban" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Room ID"`
+	UserId uint `v:"required|min:1" dc:"ID of the banned user"`
+}
+
+// UnbanRes is the response for lifting a ban
+type UnbanRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// MuteReq is the request for muting a member of a chat room
+type MuteReq struct {
+	g.Meta   `path:"/chatroom/mute/{id}" method:"post" tags:"ChatRoom" summary:"Mute a member of a chat room" auth:"true"`
+	Id       uint   `v:"required|min:1" dc:"Room ID"`
+	UserId   uint   `v:"required|min:1" dc:"ID of the member to mute"`
+	Duration int    `v:"min:0" dc:"Mute duration in seconds, 0 for a permanent mute"`
+	Reason   string `v:"max-length:200" dc:"Reason shown to the room (max 200 chars)"`
+}
+
+// MuteRes is the response for muting a member
+type MuteRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// UnmuteReq is the request for lifting a mute
+type UnmuteReq struct {
+	g.Meta `path:"/chatroom/unmute/{id}" method:"post" tags:"ChatRoom" summary:"Lift a mute" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Room ID"`
+	UserId uint `v:"required|min:1" dc:"ID of the muted member"`
+}
+
+// UnmuteRes is the response for lifting a mute
+type UnmuteRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// RestrictionListReq is the request for listing the active bans and mutes of a chat room
+type RestrictionListReq struct {
+	g.Meta `path:"/chatroom/restrictions/{id}" method:"get" tags:"ChatRoom" summary:"List active bans and mutes" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Room ID"`
+}
+
+// RestrictionListRes is the response for listing active bans and mutes
+type RestrictionListRes struct {
+	List []Restriction `json:"list" dc:"List of active restrictions"`
+}
+
+// Restriction is an active ban or mute in a chat room
+type Restriction struct {
+	UserId      uint   `json:"userId" dc:"Restricted user ID"`
+	Nickname    string `json:"nickname" dc:"Restricted user's nickname"`
+	Type        string `json:"type" dc:"Restriction type: ban or mute"`
+	ModeratorId uint   `json:"moderatorId" dc:"ID of the moderator who imposed it"`
+	Reason      string `json:"reason" dc:"Reason given by the moderator"`
+	ExpiresAt   string `json:"expiresAt" dc:"Expiration time, empty for permanent"`
+	CreatedAt   string `json:"createdAt" dc:"Time the restriction was imposed"`
+}
+
+// ModerationLogReq is the request for viewing the moderation log of a chat room
+type ModerationLogReq struct {
+	g.Meta `path:"/chatroom/moderation-log/{id}" method:"get" tags:"ChatRoom" summary:"View the moderation log" auth:"true"`
+	Id     uint `v:"required|min:1" dc:"Room ID"`
+	Page   int  `d:"1" v:"min:1" dc:"Page number, starting from 1"`
+	Size   int  `d:"20" v:"max:100" dc:"Page size, maximum 100"`
+}
+
+// ModerationLogRes is the response for viewing the moderation log
+type ModerationLogRes struct {
+	List  []ModerationLog `json:"list" dc:"Moderation log entries, newest first"`
+	Total int             `json:"total" dc:"Total number of entries"`
+	Page  int             `json:"page" dc:"Current page number"`
+	Size  int             `json:"size" dc:"Page size"`
+}
+
+// ModerationLog is a moderation action taken in a chat room
+type ModerationLog struct {
+	Id           uint   `json:"id" dc:"Log entry ID"`
+	ModeratorId  uint   `json:"moderatorId" dc:"ID of the acting moderator"`
+	TargetUserId uint   `json:"targetUserId" dc:"ID of the affected user"`
+	Action       string `json:"action" dc:"Action: kick, ban, unban, mute or unmute"`
+	Reason       string `json:"reason" dc:"Reason given by the moderator"`
+	ExpiresAt    string `json:"expiresAt" dc:"Expiration of a ban or mute, empty for permanent"`
+	CreatedAt    string `json:"createdAt" dc:"Time of the action"`
+}
@@ -8,7 +8,7 @@ import (
 type RegisterReq struct {
 	g.Meta   `path:"/user/register" method:"post" tags:"User" summary:"Register a new user"`
 	Username string `v:"required|length:5,30|regex:[a-zA-Z0-9_]+" dc:"Username (5-30 chars, alphanumeric and underscore only)"`
-	Password string `v:"required|length:6,30" dc:"Password (6-30 chars)"`
+	Password string `v:"required|length:6,30|password-max-bytes" dc:"Password (6-30 chars, at most 72 bytes)"`
 	Nickname string `v:"required|length:2,20" dc:"Display name (2-20 chars)"`
 }
 
@@ -28,11 +28,46 @@ type LoginReq struct {
 
 // LoginRes is the response for user login
 type LoginRes struct {
-	Token    string `json:"token" dc:"JWT token for authentication"`
-	Id       uint   `json:"id" dc:"User ID"`
-	Username string `json:"username" dc:"Username"`
-	Nickname string `json:"nickname" dc:"Display name"`
-	Avatar   string `json:"avatar" dc:"User avatar URL"`
+	Token        string `json:"token" dc:"JWT access token for authentication"`
+	RefreshToken string `json:"refreshToken" dc:"Refresh token for obtaining new access tokens"`
+	ExpiresIn    int64  `json:"expiresIn" dc:"Access token lifetime in seconds"`
+	Id           uint   `json:"id" dc:"User ID"`
+	Username     string `json:"username" dc:"Username"`
+	Nickname     string `json:"nickname" dc:"Display name"`
+	Avatar       string `json:"avatar" dc:"User avatar URL"`
+}
+
+// RefreshReq is the request for refreshing an access token
+type RefreshReq struct {
+	g.Meta       `path:"/user/refresh" method:"post" tags:"User" summary:"Refresh access token"`
+	RefreshToken string `v:"required" dc:"Refresh token returned by login or a previous refresh"`
+}
+
+// RefreshRes is the response for refreshing an access token
+type RefreshRes struct {
+	Token        string `json:"token" dc:"New JWT access token"`
+	RefreshToken string `json:"refreshToken" dc:"New refresh token, the old one is no longer valid"`
+	ExpiresIn    int64  `json:"expiresIn" dc:"Access token lifetime in seconds"`
+}
+
+// LogoutReq is the request for logging out the current session
+type LogoutReq struct {
+	g.Meta `path:"/user/logout" method:"post" tags:"User" summary:"Logout current session" auth:"true"`
+}
+
+// LogoutRes is the response for logging out the current session
+type LogoutRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
+}
+
+// LogoutAllReq is the request for logging out all sessions of the user
+type LogoutAllReq struct {
+	g.Meta `path:"/user/logout-all" method:"post" tags:"User" summary:"Logout all sessions" auth:"true"`
+}
+
+// LogoutAllRes is the response for logging out all sessions of the user
+type LogoutAllRes struct {
+	Success bool `json:"success" dc:"Whether the operation was successful"`
 }
 
 // ProfileReq is the request for getting user profile
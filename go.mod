@@ -9,6 +9,8 @@ require (
 	github.com/gogf/gf/v2 v2.8.3
 	github.com/golang-jwt/jwt/v4 v4.5.1
 	github.com/gorilla/websocket v1.5.3
+	golang.org/x/crypto v0.36.0
+	golang.org/x/image v0.25.0
 )
 
 require (
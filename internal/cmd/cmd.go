@@ -3,9 +3,12 @@ package cmd
 import (
 	"chatroom/internal/controller/chat"
 	"chatroom/internal/controller/chatroom"
+	"chatroom/internal/controller/upload"
 	"chatroom/internal/controller/user"
 	"chatroom/internal/dao"
 	"chatroom/internal/middleware"
+	"chatroom/internal/service"
+	"chatroom/internal/storage"
 	"context"
 
 	"github.com/gogf/gf/v2/frame/g"
@@ -24,10 +27,16 @@ var (
 				return err
 			}
 
-			s := g.Server()
+			// Initialize attachment storage so misconfiguration fails at startup
+			if _, err := storage.Default(ctx); err != nil {
+				return err
+			}
 
-			// Global middleware
-			s.Use(ghttp.MiddlewareHandlerResponse)
+			// Discard expired resumable uploads and refresh tokens in the background
+			service.NewUploadService().StartCleanup(ctx)
+			service.NewJwtService().StartCleanup(ctx)
+
+			s := g.Server()
 
 			s.BindHandler("/", func(r *ghttp.Request) {
 				r.Response.RedirectTo("/index.html")
@@ -37,22 +46,28 @@ var (
 			s.Group("/", func(group *ghttp.RouterGroup) {
 				// Public routes
 				group.Group("/api", func(group *ghttp.RouterGroup) {
+					// API responses are wrapped in the common JSON format
+					group.Middleware(ghttp.MiddlewareHandlerResponse)
+
 					// User routes
 					userController := user.NewController()
 					group.Bind(
 						// Only register non-auth routes here
 						userController.Register,
 						userController.Login,
+						userController.Refresh,
 					)
 
 					// Protected routes
 					group.Group("/", func(group *ghttp.RouterGroup) {
 						group.Middleware(middleware.Auth)
 
-						// User profile routes
+						// User profile and session routes
 						group.Bind(
 							userController.Profile,
 							userController.UpdateProfile,
+							userController.Logout,
+							userController.LogoutAll,
 						)
 
 						// Chat room routes
@@ -66,10 +81,45 @@ var (
 						group.Bind(
 							chatController.GetHistory,
 							chatController.GetRoomMembers,
+							chatController.SendDirect,
+							chatController.DirectList,
+							chatController.GetUnread,
+							chatController.MarkRead,
+							chatController.SendMessage,
+							chatController.EditMessage,
+							chatController.DeleteMessage,
+							chatController.GetMessageEdits,
+							chatController.React,
+							chatController.Unreact,
+							chatController.GetThread,
+							chatController.Search,
+							chatController.NotificationList,
+							chatController.DismissNotification,
+							chatController.DismissAllNotifications,
+							chatController.UploadAttachment,
+							chatController.DownloadAttachment,
+							chatController.DownloadThumbnail,
+							chatController.StorageUsage,
+							chatController.RoomStorageUsage,
+							chatController.StorageReport,
 						)
 					})
 				})
 
+				// Resumable upload routes; the tus protocol answers with plain HTTP statuses,
+				// so these are kept out of the JSON response middleware of the API group
+				group.Group("/api/upload", func(group *ghttp.RouterGroup) {
+					uploadController := upload.NewController()
+					group.OPTIONS("/", uploadController.Options)
+					group.Group("/", func(group *ghttp.RouterGroup) {
+						group.Middleware(middleware.Auth)
+						group.POST("/", uploadController.Create)
+						group.HEAD("/{id}", uploadController.Head)
+						group.PATCH("/{id}", uploadController.Patch)
+						group.DELETE("/{id}", uploadController.Delete)
+					})
+				})
+
 				// WebSocket routes
 				group.Group("/ws", func(group *ghttp.RouterGroup) {
 					chatController := chat.NewController()
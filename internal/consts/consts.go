@@ -11,28 +11,141 @@ const (
 	MessageTypeFile   = 2
 	MessageTypeSystem = 3
 
-	// WebSocket message types
-	WsMsgTypeText         = 1 // Text message
-	WsMsgTypeJoin         = 2 // User joined
-	WsMsgTypeLeave        = 3 // User left
-	WsMsgTypeUserList     = 4 // User list update
-	WsMsgTypeError        = 5 // Error message
-	WsMsgTypeNotification = 6 // System notification
+	// WebSocket protocol version carried in the v field of every frame
+	WsProtocolVersion = 1
+
+	// WebSocket event kinds carried in the event field of frames; the type field only holds
+	// the message type of message events
+	WsMsgTypeMessage      = 1  // A message was sent, or a client sends one
+	WsMsgTypeJoin         = 2  // User joined
+	WsMsgTypeLeave        = 3  // User left
+	WsMsgTypeUserList     = 4  // User list update
+	WsMsgTypeError        = 5  // Error message
+	WsMsgTypeNotification = 6  // System notification
+	WsMsgTypeDirect       = 7  // Direct message delivered outside its conversation
+	WsMsgTypeRead         = 8  // Client marks messages read up to a message ID
+	WsMsgTypeReadReceipt  = 9  // A member has read messages up to a message ID
+	WsMsgTypeEdit         = 10 // A message was edited
+	WsMsgTypeDelete       = 11 // A message was deleted
+	WsMsgTypeReact        = 12 // Client adds a reaction to a message
+	WsMsgTypeUnreact      = 13 // Client removes a reaction from a message
+	WsMsgTypeReaction     = 14 // The reactions of a message changed
+	WsMsgTypeThreadReply  = 15 // New reply in a thread the user participates in
+	WsMsgTypeThreadUpdate = 16 // Reply count of a thread root changed
+	WsMsgTypeTyping       = 17 // A member started or stopped typing
+	WsMsgTypeSubscribe    = 18 // Client subscribes to a room; echoed once subscribed
+	WsMsgTypeUnsubscribe  = 19 // Client unsubscribes from a room; echoed once unsubscribed, with the reason if the server ended it
+	WsMsgTypeAck          = 20 // A client request with a request ID succeeded; carries the stored message of sends
+
+	// Typing states carried in the content of typing frames
+	TypingStart = "start"
+	TypingStop  = "stop"
+
+	// Read receipts are only broadcast in rooms with at most this many members
+	DefaultReadReceiptMaxMembers = 20
+
+	// Authors may edit their messages for this many seconds after sending them
+	DefaultMessageEditWindow = 900
+
+	// A message sent again with the same nonce within this many seconds is not stored twice
+	DefaultMessageNonceWindow = 86400
+
+	// Uploaded attachments may be at most this many bytes
+	DefaultAttachmentMaxSize = 10 << 20
+
+	// Images with more pixels or bytes are stored as plain files instead of being decoded
+	MaxImagePixels = 40_000_000
+	MaxImageSize   = 50 << 20
+
+	// Resumable uploads: maximum file size, bytes of unfinished uploads per user and seconds
+	// an upload is kept after the last received bytes
+	DefaultUploadMaxSize   = 1 << 30
+	DefaultUploadUserQuota = 2 << 30
+	DefaultUploadExpire    = 86400
+
+	// Bytes of stored attachments a user may own and a room may hold
+	DefaultStorageUserQuota = 5 << 30
+	DefaultStorageRoomQuota = 20 << 30
+
+	// Room types
+	RoomTypeGroup  = "group"  // Chat room users join and leave
+	RoomTypeDirect = "direct" // One-to-one conversation between two users
 
 	// JWT related constants
-	JwtExpireTime = 86400 // JWT token expire time in seconds (24 hours)
-	JwtIssuer     = "chatroom"
+	JwtAccessExpireTime  = 900    // Access token expire time in seconds (15 minutes)
+	JwtRefreshExpireTime = 604800 // Refresh token expire time in seconds (7 days)
+	JwtIssuer            = "chatroom"
 
 	// Default values
 	DefaultAvatar = "/resource/image/avatar/default.png"
 
+	// Invitation status constants
+	InvitationStatusPending  = 0
+	InvitationStatusAccepted = 1
+	InvitationStatusDeclined = 2
+
+	// Room restriction types
+	RestrictionBan  = "ban"
+	RestrictionMute = "mute"
+
+	// Notification types
+	NotificationTypeMention = "mention" // The user was mentioned by username
+	NotificationTypeHere    = "here"    // The user was online when @here was used
+	NotificationTypeAll     = "all"     // The user is a member of a room where @all was used
+
+	// At most this many users are notified of the @username mentions of a message
+	MaxMentionsPerMessage = 20
+
+	// Moderation log actions
+	ModerationKick   = "kick"
+	ModerationBan    = "ban"
+	ModerationUnban  = "unban"
+	ModerationMute   = "mute"
+	ModerationUnmute = "unmute"
+
+	// Global roles
+	RoleAdmin = "admin" // Global administrator, has every permission
+	RoleUser  = "user"  // Regular registered user
+
+	// Room roles
+	RoomRoleOwner     = "owner"     // Creator of the room
+	RoomRoleModerator = "moderator" // Promoted by the owner to help manage the room
+	RoomRoleMember    = "member"    // Regular room member
+
+	// Permissions granted to roles through the role_permissions table
+	PermRoomCreate    = "room:create"     // Create chat rooms
+	PermRoomJoin      = "room:join"       // Join chat rooms
+	PermRoomView      = "room:view"       // View room details
+	PermRoomDelete    = "room:delete"     // Delete a room
+	PermRoomPrivate   = "room:private"    // See and join private rooms without an invitation
+	PermMessageRead   = "message:read"    // Read room message history
+	PermMessageSend   = "message:send"    // Send messages to a room
+	PermMemberList    = "member:list"     // List room members
+	PermMemberSetRole = "member:set_role" // Promote or demote room moderators
+	PermMemberInvite  = "member:invite"   // Invite users and manage invite links
+	PermMemberKick    = "member:kick"     // Remove members from a room
+	PermMemberBan     = "member:ban"      // Ban and unban users from a room
+	PermMemberMute    = "member:mute"     // Mute and unmute room members
+	PermModerationLog = "moderation:log"  // View bans, mutes and the moderation log
+	PermMessageDelete = "message:delete"  // Delete other members' messages
+	PermStorageView   = "storage:view"    // View the storage usage of a room
+	PermStorageReport = "storage:report"  // View the storage usage of all users and rooms
+
+	// Error codes returned in the code field of API responses, next to the gcode codes
+	CodeQuotaExceeded = 1001 // The upload would exceed the storage quota of the user or room
+
 	// Error messages
-	ErrNotInRoom = "User is not in the chat room"
+	ErrNotInRoom        = "User is not in the chat room"
+	ErrPermissionDenied = "Permission denied"
+	ErrBanned           = "You are banned from this chat room"
+	ErrMuted            = "You are muted in this chat room"
+	ErrNotSubscribed    = "Not subscribed to the chat room"
 )
 
 // ContextKey is the key type for context values
 type ContextKey string
 
 const (
-	ContextKeyUser ContextKey = "user" // Context key for storing user information
+	ContextKeyUser   ContextKey = "user"   // Context key for storing user information
+	ContextKeyClaims ContextKey = "claims" // Context key for storing the parsed JWT claims
 )
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// UploadAttachment stores a file to be sent to a chat room
+func (c *Controller) UploadAttachment(ctx context.Context, req *chat.UploadAttachmentReq) (res *chat.UploadAttachmentRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	attachment, err := c.messageService.UploadAttachment(ctx, ctxUser.Id, req.RoomId, req.File)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.UploadAttachmentRes{Attachment: attachment}, nil
+}
+
+// DownloadAttachment writes the contents of an attachment to the response
+func (c *Controller) DownloadAttachment(ctx context.Context, req *chat.DownloadAttachmentReq) (res *chat.DownloadAttachmentRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	attachment, content, err := c.messageService.OpenAttachment(ctx, ctxUser.Id, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	// Only images are displayed inline; everything else is downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		disposition = "inline"
+	}
+	return nil, writeContent(ctx, content, attachment.MimeType, disposition+"; filename*=UTF-8''"+url.PathEscape(attachment.Name))
+}
+
+// DownloadThumbnail writes the contents of an image thumbnail to the response
+func (c *Controller) DownloadThumbnail(ctx context.Context, req *chat.DownloadThumbnailReq) (res *chat.DownloadThumbnailRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	thumbnail, content, err := c.messageService.OpenThumbnail(ctx, ctxUser.Id, req.Id, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return nil, writeContent(ctx, content, thumbnail.MimeType, "inline")
+}
+
+// writeContent writes stored contents to the response
+func writeContent(ctx context.Context, content io.Reader, mimeType, disposition string) error {
+	r := g.RequestFromCtx(ctx)
+	r.Response.Header().Set("Content-Type", mimeType)
+	r.Response.Header().Set("Content-Disposition", disposition)
+	r.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	// Writing to the response buffer keeps the response middleware from wrapping the contents
+	_, err := io.Copy(r.Response.BufferWriter, content)
+	return err
+}
@@ -13,15 +13,19 @@ import (
 
 // Controller handles chat-related requests
 type Controller struct {
-	wsManager      *service.WebSocketManager
-	messageService *service.MessageService
+	wsManager           *service.WebSocketManager
+	messageService      *service.MessageService
+	notificationService *service.NotificationService
+	quotaService        *service.QuotaService
 }
 
 // NewController creates a new chat controller
 func NewController() *Controller {
 	return &Controller{
-		wsManager:      service.GetWebSocketManager(),
-		messageService: service.NewMessageService(),
+		wsManager:           service.GetWebSocketManager(),
+		messageService:      service.NewMessageService(),
+		notificationService: service.NewNotificationService(),
+		quotaService:        service.NewQuotaService(),
 	}
 }
 
@@ -46,9 +50,9 @@ func (c *Controller) Connect(r *ghttp.Request) {
 		return
 	}
 
-	// Parse token
+	// Parse token, rejecting revoked ones
 	jwtService := service.NewJwtService()
-	claims, err := jwtService.ParseToken(token)
+	claims, err := jwtService.ValidateToken(r.Context(), token)
 	if err != nil {
 		r.Response.WriteJson(ghttp.DefaultHandlerResponse{
 			Code:    1,
@@ -69,7 +73,7 @@ func (c *Controller) Connect(r *ghttp.Request) {
 	}
 
 	// Handle WebSocket connection
-	c.wsManager.HandleWebSocket(r, user, req.RoomId)
+	c.wsManager.HandleWebSocket(r, user, req.RoomId, req.Seq, claims.SessionId)
 }
 
 // GetHistory returns chat message history
@@ -0,0 +1,20 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// SendDirect sends a direct message to another user
+func (c *Controller) SendDirect(ctx context.Context, req *chat.DirectSendReq) (res *chat.DirectSendRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.SendDirect(ctx, ctxUser.Id, req)
+}
+
+// DirectList returns the direct conversations of the current user
+func (c *Controller) DirectList(ctx context.Context, req *chat.DirectListReq) (res *chat.DirectListRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.ListDirect(ctx, ctxUser.Id)
+}
@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// SendMessage sends a message to a room as the current user
+func (c *Controller) SendMessage(ctx context.Context, req *chat.SendMessageReq) (res *chat.SendMessageRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.SendMessage(ctx, ctxUser.Id, req)
+}
+
+// EditMessage edits a message sent by the current user
+func (c *Controller) EditMessage(ctx context.Context, req *chat.EditMessageReq) (res *chat.EditMessageRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.EditMessage(ctx, ctxUser.Id, req)
+}
+
+// DeleteMessage deletes a message
+func (c *Controller) DeleteMessage(ctx context.Context, req *chat.DeleteMessageReq) (res *chat.DeleteMessageRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.DeleteMessage(ctx, ctxUser.Id, req)
+}
+
+// GetMessageEdits returns the edit history of a message
+func (c *Controller) GetMessageEdits(ctx context.Context, req *chat.MessageEditsReq) (res *chat.MessageEditsRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.GetMessageEdits(ctx, ctxUser.Id, req)
+}
+
+// GetThread returns a thread root message with its replies
+func (c *Controller) GetThread(ctx context.Context, req *chat.ThreadReq) (res *chat.ThreadRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.GetThread(ctx, ctxUser.Id, req)
+}
+
+// Search searches the messages of the current user's rooms
+func (c *Controller) Search(ctx context.Context, req *chat.SearchReq) (res *chat.SearchRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.Search(ctx, ctxUser.Id, req)
+}
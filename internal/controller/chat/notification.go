@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// NotificationList returns the notifications of the current user
+func (c *Controller) NotificationList(ctx context.Context, req *chat.NotificationListReq) (res *chat.NotificationListRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.notificationService.List(ctx, ctxUser.Id, req)
+}
+
+// DismissNotification marks a notification of the current user read
+func (c *Controller) DismissNotification(ctx context.Context, req *chat.DismissNotificationReq) (res *chat.DismissNotificationRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	if err := c.notificationService.Dismiss(ctx, ctxUser.Id, req.Id); err != nil {
+		return nil, err
+	}
+	return &chat.DismissNotificationRes{Success: true}, nil
+}
+
+// DismissAllNotifications marks all notifications of the current user read
+func (c *Controller) DismissAllNotifications(ctx context.Context, req *chat.DismissAllNotificationsReq) (res *chat.DismissAllNotificationsRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	if err := c.notificationService.DismissAll(ctx, ctxUser.Id); err != nil {
+		return nil, err
+	}
+	return &chat.DismissAllNotificationsRes{Success: true}, nil
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// React adds an emoji reaction of the current user to a message
+func (c *Controller) React(ctx context.Context, req *chat.ReactReq) (res *chat.ReactRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	reactions, err := c.messageService.AddReaction(ctx, ctxUser.Id, req.Id, req.Emoji)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.ReactRes{Reactions: reactions}, nil
+}
+
+// Unreact removes an emoji reaction of the current user from a message
+func (c *Controller) Unreact(ctx context.Context, req *chat.UnreactReq) (res *chat.UnreactRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	reactions, err := c.messageService.RemoveReaction(ctx, ctxUser.Id, req.Id, req.Emoji)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.UnreactRes{Reactions: reactions}, nil
+}
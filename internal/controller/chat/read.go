@@ -0,0 +1,23 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// GetUnread returns the unread message counts of the current user's rooms
+func (c *Controller) GetUnread(ctx context.Context, req *chat.UnreadReq) (res *chat.UnreadRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.messageService.GetUnread(ctx, ctxUser.Id)
+}
+
+// MarkRead marks the messages of a room read up to the given message
+func (c *Controller) MarkRead(ctx context.Context, req *chat.MarkReadReq) (res *chat.MarkReadRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	if err := c.messageService.MarkRead(ctx, ctxUser.Id, req.RoomId, req.MessageId); err != nil {
+		return nil, err
+	}
+	return &chat.MarkReadRes{Success: true}, nil
+}
@@ -0,0 +1,26 @@
+package chat
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// StorageUsage returns the storage usage of the current user
+func (c *Controller) StorageUsage(ctx context.Context, req *chat.StorageUsageReq) (res *chat.StorageUsageRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.quotaService.UserUsage(ctx, ctxUser.Id)
+}
+
+// RoomStorageUsage returns the storage usage of a room
+func (c *Controller) RoomStorageUsage(ctx context.Context, req *chat.RoomStorageUsageReq) (res *chat.RoomStorageUsageRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.quotaService.RoomUsage(ctx, ctxUser.Id, req.RoomId)
+}
+
+// StorageReport returns the storage usage of all users and rooms
+func (c *Controller) StorageReport(ctx context.Context, req *chat.StorageReportReq) (res *chat.StorageReportRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.quotaService.Report(ctx, ctxUser.Id, req)
+}
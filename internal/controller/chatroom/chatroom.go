@@ -28,12 +28,14 @@ func (c *Controller) Create(ctx context.Context, req *chatroom.CreateReq) (res *
 
 // List returns a list of available chat rooms
 func (c *Controller) List(ctx context.Context, req *chatroom.ListReq) (res *chatroom.ListRes, err error) {
-	return c.roomService.List(ctx, req)
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.List(ctx, ctxUser.Id, req)
 }
 
 // Detail returns details of a specific chat room
 func (c *Controller) Detail(ctx context.Context, req *chatroom.DetailReq) (res *chatroom.DetailRes, err error) {
-	return c.roomService.Detail(ctx, req)
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Detail(ctx, ctxUser.Id, req)
 }
 
 // Join handles a user joining a chat room
@@ -53,3 +55,9 @@ func (c *Controller) Delete(ctx context.Context, req *chatroom.DeleteReq) (res *
 	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
 	return c.roomService.Delete(ctx, ctxUser.Id, req)
 }
+
+// SetRole handles promoting or demoting a room member
+func (c *Controller) SetRole(ctx context.Context, req *chatroom.SetRoleReq) (res *chatroom.SetRoleRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.SetRole(ctx, ctxUser.Id, req)
+}
@@ -0,0 +1,50 @@
+package chatroom
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// Invite handles inviting a user to a chat room
+func (c *Controller) Invite(ctx context.Context, req *chatroom.InviteReq) (res *chatroom.InviteRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Invite(ctx, ctxUser.Id, req)
+}
+
+// InvitationList returns the pending invitations of the current user
+func (c *Controller) InvitationList(ctx context.Context, req *chatroom.InvitationListReq) (res *chatroom.InvitationListRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.ListInvitations(ctx, ctxUser.Id)
+}
+
+// AcceptInvitation handles accepting an invitation
+func (c *Controller) AcceptInvitation(ctx context.Context, req *chatroom.AcceptInvitationReq) (res *chatroom.AcceptInvitationRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.AcceptInvitation(ctx, ctxUser.Id, req)
+}
+
+// DeclineInvitation handles declining an invitation
+func (c *Controller) DeclineInvitation(ctx context.Context, req *chatroom.DeclineInvitationReq) (res *chatroom.DeclineInvitationRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.DeclineInvitation(ctx, ctxUser.Id, req)
+}
+
+// CreateInviteLink handles creating a shareable invite link
+func (c *Controller) CreateInviteLink(ctx context.Context, req *chatroom.CreateInviteLinkReq) (res *chatroom.CreateInviteLinkRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.CreateInviteLink(ctx, ctxUser.Id, req)
+}
+
+// InviteLinkList returns the active invite links of a chat room
+func (c *Controller) InviteLinkList(ctx context.Context, req *chatroom.InviteLinkListReq) (res *chatroom.InviteLinkListRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.ListInviteLinks(ctx, ctxUser.Id, req)
+}
+
+// RevokeInviteLink handles revoking an invite link
+func (c *Controller) RevokeInviteLink(ctx context.Context, req *chatroom.RevokeInviteLinkReq) (res *chatroom.RevokeInviteLinkRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.RevokeInviteLink(ctx, ctxUser.Id, req)
+}
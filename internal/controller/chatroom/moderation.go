@@ -0,0 +1,50 @@
+package chatroom
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+)
+
+// Kick handles kicking a member out of a chat room
+func (c *Controller) Kick(ctx context.Context, req *chatroom.KickReq) (res *chatroom.KickRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Kick(ctx, ctxUser.Id, req)
+}
+
+// Ban handles banning a user from a chat room
+func (c *Controller) Ban(ctx context.Context, req *chatroom.BanReq) (res *chatroom.BanRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Ban(ctx, ctxUser.Id, req)
+}
+
+// Unban handles lifting a ban
+func (c *Controller) Unban(ctx context.Context, req *chatroom.UnbanReq) (res *chatroom.UnbanRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Unban(ctx, ctxUser.Id, req)
+}
+
+// Mute handles muting a member of a chat room
+func (c *Controller) Mute(ctx context.Context, req *chatroom.MuteReq) (res *chatroom.MuteRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Mute(ctx, ctxUser.Id, req)
+}
+
+// Unmute handles lifting a mute
+func (c *Controller) Unmute(ctx context.Context, req *chatroom.UnmuteReq) (res *chatroom.UnmuteRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.Unmute(ctx, ctxUser.Id, req)
+}
+
+// RestrictionList returns the active bans and mutes of a chat room
+func (c *Controller) RestrictionList(ctx context.Context, req *chatroom.RestrictionListReq) (res *chatroom.RestrictionListRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.ListRestrictions(ctx, ctxUser.Id, req)
+}
+
+// ModerationLog returns the moderation log of a chat room
+func (c *Controller) ModerationLog(ctx context.Context, req *chatroom.ModerationLogReq) (res *chatroom.ModerationLogRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.roomService.ModerationLog(ctx, ctxUser.Id, req)
+}
@@ -0,0 +1,193 @@
+package upload
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"chatroom/internal/service"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// tus protocol constants, see https://tus.io/protocols/resumable-upload
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+// Controller implements the core protocol of tus and its creation, expiration and termination
+// extensions. Responses are plain HTTP statuses and headers as the protocol requires.
+type Controller struct {
+	uploadService *service.UploadService
+}
+
+// NewController creates a new upload controller
+func NewController() *Controller {
+	return &Controller{
+		uploadService: service.NewUploadService(),
+	}
+}
+
+// Options describes the supported protocol version, extensions and maximum size
+func (c *Controller) Options(r *ghttp.Request) {
+	r.Response.Header().Set("Tus-Resumable", tusVersion)
+	r.Response.Header().Set("Tus-Version", tusVersion)
+	r.Response.Header().Set("Tus-Extension", tusExtensions)
+	if maxSize := c.uploadService.MaxSize(r.Context()); maxSize > 0 {
+		r.Response.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	r.Response.WriteHeader(http.StatusNoContent)
+}
+
+// Create starts an upload. The target room is passed as roomId and the file name as filename
+// in Upload-Metadata.
+func (c *Controller) Create(r *ghttp.Request) {
+	if !checkVersion(r) {
+		return
+	}
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		writeError(r, http.StatusBadRequest, "Upload-Length is required")
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		writeError(r, http.StatusBadRequest, err.Error())
+		return
+	}
+	roomId, err := strconv.ParseUint(metadata["roomId"], 10, 32)
+	if err != nil || roomId == 0 {
+		writeError(r, http.StatusBadRequest, "roomId is required in Upload-Metadata")
+		return
+	}
+
+	ctxUser := r.GetCtxVar(consts.ContextKeyUser).Val().(*entity.User)
+	upload, err := c.uploadService.Create(r.Context(), ctxUser.Id, uint(roomId), metadata["filename"], size)
+	if err != nil {
+		writeServiceError(r, err)
+		return
+	}
+	r.Response.Header().Set("Location", r.URL.Path+"/"+upload.Id)
+	writeUpload(r, upload, http.StatusCreated)
+}
+
+// Head returns the offset of an upload to resume from
+func (c *Controller) Head(r *ghttp.Request) {
+	if !checkVersion(r) {
+		return
+	}
+	ctxUser := r.GetCtxVar(consts.ContextKeyUser).Val().(*entity.User)
+	upload, err := c.uploadService.Get(r.Context(), ctxUser.Id, r.GetRouter("id").String())
+	if err != nil {
+		writeServiceError(r, err)
+		return
+	}
+	r.Response.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	r.Response.Header().Set("Cache-Control", "no-store")
+	writeUpload(r, upload, http.StatusOK)
+}
+
+// Patch appends the request body to an upload at Upload-Offset
+func (c *Controller) Patch(r *ghttp.Request) {
+	if !checkVersion(r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != tusContentType {
+		writeError(r, http.StatusUnsupportedMediaType, "Content-Type must be "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(r, http.StatusBadRequest, "Upload-Offset is required")
+		return
+	}
+
+	ctxUser := r.GetCtxVar(consts.ContextKeyUser).Val().(*entity.User)
+	upload, err := c.uploadService.Append(r.Context(), ctxUser.Id, r.GetRouter("id").String(), offset, r.Body)
+	if err != nil {
+		// Report the received bytes even when the rest of the body was lost
+		if upload != nil {
+			r.Response.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+		writeServiceError(r, err)
+		return
+	}
+	writeUpload(r, upload, http.StatusNoContent)
+}
+
+// Delete terminates an upload, discarding the received bytes
+func (c *Controller) Delete(r *ghttp.Request) {
+	if !checkVersion(r) {
+		return
+	}
+	ctxUser := r.GetCtxVar(consts.ContextKeyUser).Val().(*entity.User)
+	if err := c.uploadService.Terminate(r.Context(), ctxUser.Id, r.GetRouter("id").String()); err != nil {
+		writeServiceError(r, err)
+		return
+	}
+	r.Response.Header().Set("Tus-Resumable", tusVersion)
+	r.Response.WriteHeader(http.StatusNoContent)
+}
+
+// checkVersion rejects requests for other protocol versions
+func checkVersion(r *ghttp.Request) bool {
+	if r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	r.Response.Header().Set("Tus-Version", tusVersion)
+	writeError(r, http.StatusPreconditionFailed, "Unsupported Tus-Resumable version")
+	return false
+}
+
+// writeUpload answers with the offset and expiry of an upload
+func writeUpload(r *ghttp.Request, upload *entity.Upload, status int) {
+	r.Response.Header().Set("Tus-Resumable", tusVersion)
+	r.Response.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Offset < upload.Size {
+		r.Response.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	r.Response.WriteHeader(status)
+}
+
+// writeServiceError answers with the HTTP status carried by an upload error, 500 for other errors
+func writeServiceError(r *ghttp.Request, err error) {
+	status := gerror.Code(err).Code()
+	if status < http.StatusBadRequest || status > 599 {
+		g.Log().Error(r.Context(), "Upload failed:", err)
+		status = http.StatusInternalServerError
+	}
+	writeError(r, status, err.Error())
+}
+
+// writeError answers with a status and a plain text message
+func writeError(r *ghttp.Request, status int, message string) {
+	r.Response.Header().Set("Tus-Resumable", tusVersion)
+	r.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	r.Response.WriteHeader(status)
+	r.Response.Write(message)
+}
+
+// parseMetadata decodes Upload-Metadata: comma-separated keys, each followed by a space and a
+// base64-encoded value, which may be omitted
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, gerror.Newf("Invalid Upload-Metadata value of %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -30,6 +30,24 @@ func (c *Controller) Login(ctx context.Context, req *user.LoginReq) (res *user.L
 	return c.userService.Login(ctx, req)
 }
 
+// Refresh exchanges a refresh token for a new token pair
+func (c *Controller) Refresh(ctx context.Context, req *user.RefreshReq) (res *user.RefreshRes, err error) {
+	return c.userService.Refresh(ctx, req)
+}
+
+// Logout logs out the current session
+func (c *Controller) Logout(ctx context.Context, req *user.LogoutReq) (res *user.LogoutRes, err error) {
+	// Get token claims from context (set by auth middleware)
+	claims := ctx.Value(consts.ContextKeyClaims).(*service.JwtClaims)
+	return c.userService.Logout(ctx, claims.SessionId)
+}
+
+// LogoutAll logs out every session of the current user
+func (c *Controller) LogoutAll(ctx context.Context, req *user.LogoutAllReq) (res *user.LogoutAllRes, err error) {
+	ctxUser := ctx.Value(consts.ContextKeyUser).(*entity.User)
+	return c.userService.LogoutAll(ctx, ctxUser.Id)
+}
+
 // Profile gets user profile information
 func (c *Controller) Profile(ctx context.Context, req *user.ProfileReq) (res *user.ProfileRes, err error) {
 	// Get user from context (set by auth middleware)
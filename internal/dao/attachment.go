@@ -0,0 +1,206 @@
+package dao
+
+import (
+	"chatroom/internal/model/entity"
+	"context"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// AttachmentDao handles database operations for attachment metadata
+type AttachmentDao struct{}
+
+// Attachment table names
+const (
+	AttachmentTable          = "attachments"
+	AttachmentThumbnailTable = "attachment_thumbnails"
+)
+
+// StorageUsage is the number and total size of the attachments owned by a user or held by a room.
+// Thumbnails are not counted. Name is the username or room name, only set in top lists.
+type StorageUsage struct {
+	Id    uint   `json:"id"`
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// NewAttachmentDao returns a new AttachmentDao instance
+func NewAttachmentDao() *AttachmentDao {
+	return &AttachmentDao{}
+}
+
+// Create stores the metadata of an uploaded file and its thumbnails and returns its ID
+func (dao *AttachmentDao) Create(ctx context.Context, attachment *entity.Attachment, thumbnails []entity.AttachmentThumbnail) (uint, error) {
+	var id int64
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		result, err := Model(ctx, AttachmentTable).Data(g.Map{
+			"user_id":     attachment.UserId,
+			"room_id":     attachment.RoomId,
+			"storage_key": attachment.StorageKey,
+			"name":        attachment.Name,
+			"size":        attachment.Size,
+			"mime_type":   attachment.MimeType,
+			"checksum":    attachment.Checksum,
+			"width":       attachment.Width,
+			"height":      attachment.Height,
+		}).Insert()
+		if err != nil {
+			return err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		for _, thumbnail := range thumbnails {
+			_, err = Model(ctx, AttachmentThumbnailTable).Data(g.Map{
+				"attachment_id": id,
+				"size":          thumbnail.Size,
+				"width":         thumbnail.Width,
+				"height":        thumbnail.Height,
+				"storage_key":   thumbnail.StorageKey,
+				"mime_type":     thumbnail.MimeType,
+			}).Insert()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return uint(id), err
+}
+
+// Delete deletes the metadata of an attachment and its thumbnails
+func (dao *AttachmentDao) Delete(ctx context.Context, id uint) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := Model(ctx, AttachmentThumbnailTable).Where("attachment_id", id).Delete(); err != nil {
+			return err
+		}
+		_, err := Model(ctx, AttachmentTable).Where("id", id).Delete()
+		return err
+	})
+}
+
+// GetByID retrieves an attachment by ID
+func (dao *AttachmentDao) GetByID(ctx context.Context, id uint) (*entity.Attachment, error) {
+	var attachment *entity.Attachment
+	err := Model(ctx, AttachmentTable).Where("id", id).Scan(&attachment)
+	return attachment, err
+}
+
+// ListByIds retrieves the attachments with the given IDs
+func (dao *AttachmentDao) ListByIds(ctx context.Context, ids []uint) (attachments []entity.Attachment, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	err = Model(ctx, AttachmentTable).WhereIn("id", ids).Scan(&attachments)
+	return
+}
+
+// GetThumbnail retrieves the thumbnail of an attachment for a bounding box size
+func (dao *AttachmentDao) GetThumbnail(ctx context.Context, attachmentId uint, size int) (*entity.AttachmentThumbnail, error) {
+	var thumbnail *entity.AttachmentThumbnail
+	err := Model(ctx, AttachmentThumbnailTable).
+		Where("attachment_id", attachmentId).
+		Where("size", size).
+		Scan(&thumbnail)
+	return thumbnail, err
+}
+
+// ListThumbnails retrieves the thumbnails of the attachments with the given IDs, smallest first
+func (dao *AttachmentDao) ListThumbnails(ctx context.Context, attachmentIds []uint) (thumbnails []entity.AttachmentThumbnail, err error) {
+	if len(attachmentIds) == 0 {
+		return nil, nil
+	}
+	err = Model(ctx, AttachmentThumbnailTable).
+		WhereIn("attachment_id", attachmentIds).
+		Order("attachment_id ASC, size ASC").
+		Scan(&thumbnails)
+	return
+}
+
+// GetUserUsage returns the storage usage of the attachments uploaded by a user
+func (dao *AttachmentDao) GetUserUsage(ctx context.Context, userId uint) (*StorageUsage, error) {
+	usage := &StorageUsage{Id: userId}
+	err := Model(ctx, AttachmentTable).
+		Fields("COUNT(1) AS files, IFNULL(SUM(size), 0) AS bytes").
+		Where("user_id", userId).
+		Scan(usage)
+	return usage, err
+}
+
+// GetRoomUsage returns the storage usage of the attachments uploaded to a room
+func (dao *AttachmentDao) GetRoomUsage(ctx context.Context, roomId uint) (*StorageUsage, error) {
+	usage := &StorageUsage{Id: roomId}
+	err := Model(ctx, AttachmentTable).
+		Fields("COUNT(1) AS files, IFNULL(SUM(size), 0) AS bytes").
+		Where("room_id", roomId).
+		Scan(usage)
+	return usage, err
+}
+
+// ListTopUsers returns the users whose attachments take the most space, largest first
+func (dao *AttachmentDao) ListTopUsers(ctx context.Context, limit int) (usages []StorageUsage, err error) {
+	err = Model(ctx, AttachmentTable).
+		As("a").
+		LeftJoin("users u", "u.id = a.user_id").
+		Fields("a.user_id AS id, IFNULL(u.username, '') AS name, COUNT(1) AS files, SUM(a.size) AS bytes").
+		Group("a.user_id").
+		Order("bytes DESC, id ASC").
+		Limit(limit).
+		Scan(&usages)
+	return
+}
+
+// ListTopRooms returns the rooms whose attachments take the most space, largest first
+func (dao *AttachmentDao) ListTopRooms(ctx context.Context, limit int) (usages []StorageUsage, err error) {
+	err = Model(ctx, AttachmentTable).
+		As("a").
+		LeftJoin("chatrooms cr", "cr.id = a.room_id").
+		Fields("a.room_id AS id, IFNULL(cr.name, '') AS name, COUNT(1) AS files, SUM(a.size) AS bytes").
+		Group("a.room_id").
+		Order("bytes DESC, id ASC").
+		Limit(limit).
+		Scan(&usages)
+	return
+}
+
+// GetTotalUsage returns the storage usage of all attachments
+func (dao *AttachmentDao) GetTotalUsage(ctx context.Context) (*StorageUsage, error) {
+	usage := &StorageUsage{}
+	err := Model(ctx, AttachmentTable).
+		Fields("COUNT(1) AS files, IFNULL(SUM(size), 0) AS bytes").
+		Scan(usage)
+	return usage, err
+}
+
+// ListStorageKeys returns the storage keys of an attachment and its thumbnails
+func (dao *AttachmentDao) ListStorageKeys(ctx context.Context, id uint) ([]string, error) {
+	return dao.listStorageKeys(ctx, "id = ?", id)
+}
+
+// ListStorageKeysByRoom returns the storage keys of all attachments uploaded to a room and their thumbnails
+func (dao *AttachmentDao) ListStorageKeysByRoom(ctx context.Context, roomId uint) ([]string, error) {
+	return dao.listStorageKeys(ctx, "room_id = ?", roomId)
+}
+
+// listStorageKeys returns the storage keys of the attachments matching a condition and their thumbnails
+func (dao *AttachmentDao) listStorageKeys(ctx context.Context, where string, arg interface{}) ([]string, error) {
+	values, err := Model(ctx, AttachmentTable).Where(where, arg).Array("storage_key")
+	if err != nil {
+		return nil, err
+	}
+	thumbnailValues, err := Model(ctx, AttachmentThumbnailTable).
+		Where("attachment_id IN (SELECT id FROM attachments WHERE "+where+")", arg).
+		Array("storage_key")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values)+len(thumbnailValues))
+	for _, value := range append(values, thumbnailValues...) {
+		keys = append(keys, value.String())
+	}
+	return keys, nil
+}
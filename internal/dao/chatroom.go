@@ -1,10 +1,13 @@
 package dao
 
 import (
+	"chatroom/internal/consts"
 	"chatroom/internal/model/entity"
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
@@ -46,16 +49,82 @@ func (dao *ChatRoomDao) Create(ctx context.Context, chatRoom *entity.ChatRoom) (
 		return 0, err
 	}
 
-	// Add creator as a member of the room
+	// Add creator as the owner of the room
 	_, err = Model(ctx, RoomUserTable).Data(g.Map{
 		"room_id":   id,
 		"user_id":   chatRoom.CreatorId,
+		"role":      consts.RoomRoleOwner,
 		"joined_at": time.Now(),
 	}).Insert()
 
 	return uint(id), err
 }
 
+// GetDirectRoom retrieves the direct conversation between two users, or nil if they have none
+func (dao *ChatRoomDao) GetDirectRoom(ctx context.Context, userId, peerId uint) (*entity.ChatRoom, error) {
+	var chatRoom *entity.ChatRoom
+	err := Model(ctx, ChatRoomTable).Where("dm_key", DirectRoomKey(userId, peerId)).Scan(&chatRoom)
+	return chatRoom, err
+}
+
+// CreateDirectRoom creates the direct conversation between two users with both of them as members
+func (dao *ChatRoomDao) CreateDirectRoom(ctx context.Context, userId, peerId uint) (uint, error) {
+	var roomId uint
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		result, err := Model(ctx, ChatRoomTable).Data(g.Map{
+			"name":       "",
+			"creator_id": userId,
+			"is_private": true,
+			"type":       consts.RoomTypeDirect,
+			"dm_key":     DirectRoomKey(userId, peerId),
+		}).Insert()
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		roomId = uint(id)
+
+		for _, memberId := range []uint{userId, peerId} {
+			_, err = Model(ctx, RoomUserTable).Data(g.Map{
+				"room_id":   roomId,
+				"user_id":   memberId,
+				"role":      consts.RoomRoleMember,
+				"joined_at": time.Now(),
+			}).Insert()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return roomId, err
+}
+
+// ListDirectRooms returns the direct conversations of a user, most recently active first
+func (dao *ChatRoomDao) ListDirectRooms(ctx context.Context, userId uint) (rooms []entity.ChatRoom, err error) {
+	err = Model(ctx, ChatRoomTable).
+		As("cr").
+		InnerJoin("room_users ru", "ru.room_id = cr.id").
+		Where("ru.user_id", userId).
+		Where("cr.type", consts.RoomTypeDirect).
+		Fields("cr.*").
+		Order(gdb.Raw("(SELECT MAX(m.id) FROM messages m WHERE m.room_id = cr.id) DESC, cr.id DESC")).
+		Scan(&rooms)
+	return
+}
+
+// DirectRoomKey returns the key identifying the direct conversation between two users
+func DirectRoomKey(userId, peerId uint) string {
+	if userId > peerId {
+		userId, peerId = peerId, userId
+	}
+	return fmt.Sprintf("%d:%d", userId, peerId)
+}
+
 // Update updates a chat room
 func (dao *ChatRoomDao) Update(ctx context.Context, id uint, data g.Map) error {
 	// Add update timestamp
@@ -67,20 +136,35 @@ func (dao *ChatRoomDao) Update(ctx context.Context, id uint, data g.Map) error {
 
 // Delete deletes a chat room
 func (dao *ChatRoomDao) Delete(ctx context.Context, id uint) error {
-	// First delete all room-user relationships
-	_, err := Model(ctx, RoomUserTable).Where("room_id", id).Delete()
+	// First delete all room-user relationships, invitations, invite links, moderation data and attachments
+	_, err := Model(ctx, AttachmentThumbnailTable).
+		Where("attachment_id IN (SELECT id FROM attachments WHERE room_id = ?)", id).
+		Delete()
 	if err != nil {
 		return err
 	}
+	tables := []string{
+		RoomUserTable, RoomInvitationTable, RoomInviteLinkTable,
+		RoomRestrictionTable, ModerationLogTable, NotificationTable, AttachmentTable, UploadTable,
+	}
+	for _, table := range tables {
+		if _, err := Model(ctx, table).Where("room_id", id).Delete(); err != nil {
+			return err
+		}
+	}
 
 	// Then delete the room
 	_, err = Model(ctx, ChatRoomTable).Where("id", id).Delete()
 	return err
 }
 
-// List returns a paginated list of chat rooms
-func (dao *ChatRoomDao) List(ctx context.Context, page, size int) (rooms []entity.ChatRoom, total int, err error) {
-	model := Model(ctx, ChatRoomTable)
+// List returns a paginated list of chat rooms visible to a user.
+// Private rooms are only included if the user is a member of them or includePrivate is set.
+func (dao *ChatRoomDao) List(ctx context.Context, userId uint, includePrivate bool, page, size int) (rooms []entity.ChatRoom, total int, err error) {
+	model := Model(ctx, ChatRoomTable).Where("type", consts.RoomTypeGroup)
+	if !includePrivate {
+		model = model.Where("is_private = ? OR id IN (SELECT room_id FROM room_users WHERE user_id = ?)", false, userId)
+	}
 
 	// Get total count
 	total, err = model.Count()
@@ -113,22 +197,38 @@ func (dao *ChatRoomDao) AddUser(ctx context.Context, roomId, userId uint) error
 		return nil
 	}
 
+	// New members start with the existing history marked as read
+	lastMessageId, err := Model(ctx, MessageTable).Where("room_id", roomId).Max("id")
+	if err != nil {
+		return err
+	}
+
 	// Add user to room
 	_, err = Model(ctx, RoomUserTable).Data(g.Map{
-		"room_id":   roomId,
-		"user_id":   userId,
-		"joined_at": time.Now(),
+		"room_id":              roomId,
+		"user_id":              userId,
+		"role":                 consts.RoomRoleMember,
+		"last_read_message_id": uint(lastMessageId),
+		"joined_at":            time.Now(),
 	}).Insert()
 	return err
 }
 
-// RemoveUser removes a user from a chat room
+// RemoveUser removes a user from a chat room together with their notifications of the room
 func (dao *ChatRoomDao) RemoveUser(ctx context.Context, roomId, userId uint) error {
-	_, err := Model(ctx, RoomUserTable).
-		Where("room_id", roomId).
-		Where("user_id", userId).
-		Delete()
-	return err
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		// Former members can no longer open the messages they were notified about
+		for _, table := range []string{NotificationTable, RoomUserTable} {
+			_, err := Model(ctx, table).
+				Where("room_id", roomId).
+				Where("user_id", userId).
+				Delete()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // ListRoomUsers returns all users in a specific chat room
@@ -161,3 +261,47 @@ func (dao *ChatRoomDao) GetUserRooms(ctx context.Context, userId uint) (rooms []
 		Scan(&rooms)
 	return
 }
+
+// GetMemberRole returns a user's role in a chat room, or an empty string if the user is not a member
+func (dao *ChatRoomDao) GetMemberRole(ctx context.Context, roomId, userId uint) (string, error) {
+	role, err := Model(ctx, RoomUserTable).
+		Where("room_id", roomId).
+		Where("user_id", userId).
+		Value("role")
+	if err != nil {
+		return "", err
+	}
+	return role.String(), nil
+}
+
+// SetMemberRole updates a user's role in a chat room
+func (dao *ChatRoomDao) SetMemberRole(ctx context.Context, roomId, userId uint, role string) error {
+	_, err := Model(ctx, RoomUserTable).
+		Where("room_id", roomId).
+		Where("user_id", userId).
+		Data(g.Map{"role": role}).
+		Update()
+	return err
+}
+
+// MarkRead moves a member's read position forward to a message.
+// It reports whether the position changed; it never moves backwards.
+func (dao *ChatRoomDao) MarkRead(ctx context.Context, roomId, userId, messageId uint) (bool, error) {
+	result, err := Model(ctx, RoomUserTable).
+		Where("room_id", roomId).
+		Where("user_id", userId).
+		WhereLT("last_read_message_id", messageId).
+		Data(g.Map{"last_read_message_id": messageId}).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ListMembers returns the memberships of all users in a chat room
+func (dao *ChatRoomDao) ListMembers(ctx context.Context, roomId uint) (members []entity.RoomUser, err error) {
+	err = Model(ctx, RoomUserTable).Where("room_id", roomId).Scan(&members)
+	return
+}
@@ -1,8 +1,10 @@
 package dao
 
 import (
+	"chatroom/internal/consts"
 	"chatroom/internal/model/entity"
 	"context"
+	"strings"
 
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
@@ -20,6 +22,7 @@ func InitDatabase(ctx context.Context) error {
 			nickname VARCHAR(50) NOT NULL,
 			avatar VARCHAR(255) DEFAULT '',
 			status INTEGER DEFAULT 0,
+			role VARCHAR(20) DEFAULT 'user',
 			last_login DATETIME,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -38,6 +41,8 @@ func InitDatabase(ctx context.Context) error {
 			description VARCHAR(200) DEFAULT '',
 			creator_id INTEGER NOT NULL,
 			is_private BOOLEAN DEFAULT FALSE,
+			type VARCHAR(10) DEFAULT 'group',
+			dm_key VARCHAR(40),
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (creator_id) REFERENCES users(id)
@@ -56,21 +61,129 @@ func InitDatabase(ctx context.Context) error {
 			user_id INTEGER NOT NULL,
 			content TEXT NOT NULL,
 			type INTEGER DEFAULT 0,
+			parent_id INTEGER DEFAULT 0,
+			attachment_id INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			edited_at DATETIME,
+			deleted_at DATETIME,
+			deleted_by INTEGER DEFAULT 0,
+			seq INTEGER DEFAULT 0,
+			nonce VARCHAR(64) DEFAULT '',
 			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
 			FOREIGN KEY (user_id) REFERENCES users(id)
-		)
+		);
+		CREATE INDEX IF NOT EXISTS idx_messages_room_created ON messages(room_id, created_at, id);
 	`)
 	if err != nil {
 		glog.Error(ctx, "Create messages table failed:", err)
 		return err
 	}
 
+	// Create message_edits table keeping the previous contents of edited messages
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS message_edits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (message_id) REFERENCES messages(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits(message_id);
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create message_edits table failed:", err)
+		return err
+	}
+
+	// Create attachments table with the metadata of uploaded files; contents live in the storage backend
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			room_id INTEGER NOT NULL,
+			storage_key VARCHAR(100) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			size INTEGER NOT NULL,
+			mime_type VARCHAR(100) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			width INTEGER DEFAULT 0,
+			height INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_attachments_room_id ON attachments(room_id);
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create attachments table failed:", err)
+		return err
+	}
+
+	// Create attachment thumbnails table; one row per bounding box size of an image
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS attachment_thumbnails (
+			attachment_id INTEGER NOT NULL,
+			size INTEGER NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			storage_key VARCHAR(100) NOT NULL,
+			mime_type VARCHAR(100) NOT NULL,
+			PRIMARY KEY (attachment_id, size),
+			FOREIGN KEY (attachment_id) REFERENCES attachments(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create attachment thumbnails table failed:", err)
+		return err
+	}
+
+	// Create notifications table for the notification inbox of users
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type VARCHAR(20) NOT NULL,
+			room_id INTEGER NOT NULL,
+			message_id INTEGER NOT NULL,
+			actor_id INTEGER NOT NULL,
+			content TEXT,
+			is_read BOOLEAN DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
+			FOREIGN KEY (message_id) REFERENCES messages(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, is_read);
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create notifications table failed:", err)
+		return err
+	}
+
+	// Create message_reactions table for emoji reactions on messages
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS message_reactions (
+			message_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			emoji VARCHAR(32) NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, user_id, emoji),
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create message_reactions table failed:", err)
+		return err
+	}
+
 	// Create room_users table for many-to-many relationship between users and rooms
 	_, err = g.DB().Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS room_users (
 			room_id INTEGER NOT NULL,
 			user_id INTEGER NOT NULL,
+			role VARCHAR(20) DEFAULT 'member',
+			last_read_message_id INTEGER DEFAULT 0,
 			joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (room_id, user_id),
 			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
@@ -82,6 +195,167 @@ func InitDatabase(ctx context.Context) error {
 		return err
 	}
 
+	// Create role_permissions table mapping roles to the permissions they grant
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS role_permissions (
+			role VARCHAR(20) NOT NULL,
+			permission VARCHAR(50) NOT NULL,
+			PRIMARY KEY (role, permission)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create role_permissions table failed:", err)
+		return err
+	}
+
+	// Create room_invitations table for direct invitations to rooms
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS room_invitations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_id INTEGER NOT NULL,
+			inviter_id INTEGER NOT NULL,
+			invitee_id INTEGER NOT NULL,
+			status INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			responded_at DATETIME,
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
+			FOREIGN KEY (inviter_id) REFERENCES users(id),
+			FOREIGN KEY (invitee_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create room_invitations table failed:", err)
+		return err
+	}
+
+	// Create room_invite_links table for shareable invite links
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS room_invite_links (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_id INTEGER NOT NULL,
+			creator_id INTEGER NOT NULL,
+			code VARCHAR(32) UNIQUE NOT NULL,
+			max_uses INTEGER DEFAULT 0,
+			uses INTEGER DEFAULT 0,
+			revoked BOOLEAN DEFAULT FALSE,
+			expires_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
+			FOREIGN KEY (creator_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create room_invite_links table failed:", err)
+		return err
+	}
+
+	// Create room_restrictions table for active bans and mutes
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS room_restrictions (
+			room_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			type VARCHAR(10) NOT NULL,
+			moderator_id INTEGER NOT NULL,
+			reason VARCHAR(200) DEFAULT '',
+			expires_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (room_id, user_id, type),
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (moderator_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create room_restrictions table failed:", err)
+		return err
+	}
+
+	// Create moderation_logs table recording every moderation action
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS moderation_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_id INTEGER NOT NULL,
+			moderator_id INTEGER NOT NULL,
+			target_user_id INTEGER NOT NULL,
+			action VARCHAR(10) NOT NULL,
+			reason VARCHAR(200) DEFAULT '',
+			expires_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id),
+			FOREIGN KEY (moderator_id) REFERENCES users(id),
+			FOREIGN KEY (target_user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create moderation_logs table failed:", err)
+		return err
+	}
+
+	// Create refresh_tokens table for token rotation and revocation
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			session_id VARCHAR(64) NOT NULL,
+			access_jti VARCHAR(64) NOT NULL,
+			revoked BOOLEAN DEFAULT FALSE,
+			replaced_by INTEGER DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create refresh_tokens table failed:", err)
+		return err
+	}
+
+	// Create indexes for refresh token lookups and cleanup
+	_, err = g.DB().Exec(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_access_jti ON refresh_tokens(access_jti);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create refresh_tokens indexes failed:", err)
+		return err
+	}
+
+	// Create uploads table for resumable uploads in progress; received data lives in partial files
+	_, err = g.DB().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS uploads (
+			id VARCHAR(32) PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			room_id INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			size INTEGER NOT NULL,
+			upload_offset INTEGER DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (room_id) REFERENCES chatrooms(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+		CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
+	`)
+	if err != nil {
+		glog.Error(ctx, "Create uploads table failed:", err)
+		return err
+	}
+
+	// Upgrade tables created by older versions
+	if err := migrateDatabase(ctx); err != nil {
+		glog.Error(ctx, "Migrate database failed:", err)
+		return err
+	}
+
+	// Seed default role permissions
+	if err := NewRoleDao().SeedDefaults(ctx); err != nil {
+		glog.Error(ctx, "Seed role permissions failed:", err)
+		return err
+	}
+
 	// Create default admin user if not exists
 	res, err := g.DB().GetValue(ctx, "SELECT COUNT(*) FROM users WHERE username = ?", "admin")
 	if err != nil {
@@ -95,6 +369,7 @@ func InitDatabase(ctx context.Context) error {
 			Username: "admin",
 			Nickname: "管理员",
 			Password: "admin123",
+			Role:     consts.RoleAdmin,
 		}
 		_, err = userDao.Create(ctx, adminUser)
 		if err != nil {
@@ -111,3 +386,8 @@ func InitDatabase(ctx context.Context) error {
 func Model(ctx context.Context, tableName string) *gdb.Model {
 	return g.DB().Model(tableName).Safe().Ctx(ctx)
 }
+
+// IsUniqueViolation reports whether an error is a unique constraint failure of the database
+func IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
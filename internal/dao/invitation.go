@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// InvitationDao handles database operations for room invitations and invite links
+type InvitationDao struct{}
+
+// RoomInvitationTable is the name of the room invitation table
+const RoomInvitationTable = "room_invitations"
+
+// RoomInviteLinkTable is the name of the room invite link table
+const RoomInviteLinkTable = "room_invite_links"
+
+// NewInvitationDao returns a new InvitationDao instance
+func NewInvitationDao() *InvitationDao {
+	return &InvitationDao{}
+}
+
+// CreateInvitation stores a new pending invitation
+func (dao *InvitationDao) CreateInvitation(ctx context.Context, invitation *entity.RoomInvitation) (uint, error) {
+	result, err := Model(ctx, RoomInvitationTable).Data(g.Map{
+		"room_id":    invitation.RoomId,
+		"inviter_id": invitation.InviterId,
+		"invitee_id": invitation.InviteeId,
+		"status":     consts.InvitationStatusPending,
+	}).Insert()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return uint(id), err
+}
+
+// GetInvitation retrieves an invitation by ID
+func (dao *InvitationDao) GetInvitation(ctx context.Context, id uint) (*entity.RoomInvitation, error) {
+	var invitation *entity.RoomInvitation
+	err := Model(ctx, RoomInvitationTable).Where("id", id).Scan(&invitation)
+	return invitation, err
+}
+
+// GetPendingInvitation retrieves the pending invitation of a user to a room
+func (dao *InvitationDao) GetPendingInvitation(ctx context.Context, roomId, inviteeId uint) (*entity.RoomInvitation, error) {
+	var invitation *entity.RoomInvitation
+	err := Model(ctx, RoomInvitationTable).
+		Where("room_id", roomId).
+		Where("invitee_id", inviteeId).
+		Where("status", consts.InvitationStatusPending).
+		Scan(&invitation)
+	return invitation, err
+}
+
+// ListPendingInvitations returns all pending invitations of a user
+func (dao *InvitationDao) ListPendingInvitations(ctx context.Context, inviteeId uint) (invitations []entity.RoomInvitation, err error) {
+	err = Model(ctx, RoomInvitationTable).
+		Where("invitee_id", inviteeId).
+		Where("status", consts.InvitationStatusPending).
+		Order("id DESC").
+		Scan(&invitations)
+	return
+}
+
+// SetInvitationStatus records the invitee's response to an invitation
+func (dao *InvitationDao) SetInvitationStatus(ctx context.Context, id uint, status int) error {
+	_, err := Model(ctx, RoomInvitationTable).Where("id", id).Data(g.Map{
+		"status":       status,
+		"responded_at": time.Now(),
+	}).Update()
+	return err
+}
+
+// CreateLink stores a new invite link
+func (dao *InvitationDao) CreateLink(ctx context.Context, link *entity.RoomInviteLink) (uint, error) {
+	data := g.Map{
+		"room_id":    link.RoomId,
+		"creator_id": link.CreatorId,
+		"code":       link.Code,
+		"max_uses":   link.MaxUses,
+	}
+	if !link.ExpiresAt.IsZero() {
+		data["expires_at"] = link.ExpiresAt
+	}
+
+	result, err := Model(ctx, RoomInviteLinkTable).Data(data).Insert()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return uint(id), err
+}
+
+// GetLinkByCode retrieves an invite link by its code
+func (dao *InvitationDao) GetLinkByCode(ctx context.Context, code string) (*entity.RoomInviteLink, error) {
+	var link *entity.RoomInviteLink
+	err := Model(ctx, RoomInviteLinkTable).Where("code", code).Scan(&link)
+	return link, err
+}
+
+// ListLinks returns all non-revoked invite links of a room
+func (dao *InvitationDao) ListLinks(ctx context.Context, roomId uint) (links []entity.RoomInviteLink, err error) {
+	err = Model(ctx, RoomInviteLinkTable).
+		Where("room_id", roomId).
+		Where("revoked", false).
+		Order("id DESC").
+		Scan(&links)
+	return
+}
+
+// UseLink increments the use count of an invite link if it still has the given count.
+// It reports false when a concurrent join used the link first.
+func (dao *InvitationDao) UseLink(ctx context.Context, id uint, uses int) (bool, error) {
+	result, err := Model(ctx, RoomInviteLinkTable).
+		Where("id", id).
+		Where("uses", uses).
+		Data(g.Map{"uses": uses + 1}).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeLink revokes an invite link
+func (dao *InvitationDao) RevokeLink(ctx context.Context, id uint) error {
+	_, err := Model(ctx, RoomInviteLinkTable).Where("id", id).Data(g.Map{"revoked": true}).Update()
+	return err
+}
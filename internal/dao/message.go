@@ -3,6 +3,9 @@ package dao
 import (
 	"chatroom/internal/model/entity"
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
@@ -14,19 +17,53 @@ type MessageDao struct{}
 // MessageTable is the name of the message table
 const MessageTable = "messages"
 
+// MessageEditTable is the name of the message edit history table
+const MessageEditTable = "message_edits"
+
+// MessageSearchTable is the name of the full-text search index of text messages
+const MessageSearchTable = "messages_fts"
+
+// Markers wrapping the matches in search snippets
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchEnd   = "\x03"
+)
+
+// MessageSearchFilter restricts a full-text message search
+type MessageSearchFilter struct {
+	Query   string // Text to search for, matched as a phrase
+	RoomIds []uint // Rooms to search in; nothing is found if empty
+	UserId  uint   // Only messages of this sender, if set
+	From    string // Only messages created at or after this time, if set
+	Until   string // Only messages created before this time, if set
+}
+
+// RoomUnread is the read position of a member in a room and the number of messages after it
+type RoomUnread struct {
+	RoomId            uint `json:"roomId"`
+	LastReadMessageId uint `json:"lastReadMessageId"`
+	Unread            int  `json:"unread"`
+}
+
 // NewMessageDao creates a new MessageDao instance
 func NewMessageDao() *MessageDao {
 	return &MessageDao{}
 }
 
-// Create stores a new message in the database
+// Create stores a new message in the database, setting its ID and the next sequence number of
+// its room. The sequence number is taken in the same statement, so concurrent messages of a room
+// never share one.
 func (dao *MessageDao) Create(ctx context.Context, message *entity.Message) (uint, error) {
 	// Create data map without ID field
 	data := g.Map{
-		"content": message.Content,
-		"user_id": message.UserId,
-		"room_id": message.RoomId,
-		"type":    message.Type,
+		"content":       message.Content,
+		"user_id":       message.UserId,
+		"room_id":       message.RoomId,
+		"type":          message.Type,
+		"parent_id":     message.ParentId,
+		"attachment_id": message.AttachmentId,
+		"nonce":         message.Nonce,
+		"seq":           gdb.Raw(fmt.Sprintf("(SELECT IFNULL(MAX(seq), 0) + 1 FROM messages WHERE room_id = %d)", message.RoomId)),
 	}
 
 	result, err := Model(ctx, MessageTable).Data(data).Insert()
@@ -34,26 +71,166 @@ func (dao *MessageDao) Create(ctx context.Context, message *entity.Message) (uin
 		return 0, err
 	}
 	id, err := result.LastInsertId()
-	return uint(id), err
+	if err != nil {
+		return 0, err
+	}
+	seq, err := Model(ctx, MessageTable).Where("id", id).Value("seq")
+	if err != nil {
+		return 0, err
+	}
+	message.Id, message.Seq = uint(id), seq.Uint()
+	return message.Id, nil
+}
+
+// messageWithUserFields selects messages with their sender and thread statistics.
+// Deleted messages are kept as tombstones flagged by deleted.
+const messageWithUserFields = `
+	m.*,
+	m.deleted_at IS NOT NULL AS deleted,
+	(SELECT COUNT(1) FROM messages r WHERE r.parent_id = m.id AND r.deleted_at IS NULL) AS reply_count,
+	(SELECT STRFTIME('%Y-%m-%d %H:%M:%S', MAX(r.created_at)) FROM messages r WHERE r.parent_id = m.id AND r.deleted_at IS NULL) AS last_reply_at,
+	u.username,
+	u.nickname,
+	u.avatar
+`
+
+// GetRoomMessagesBefore retrieves up to limit messages of the main timeline of a room older than
+// a message, newest first, or the latest messages if beforeId is 0. Thread replies are left out.
+func (dao *MessageDao) GetRoomMessagesBefore(ctx context.Context, roomId, beforeId uint, limit int) (gdb.Result, error) {
+	model := dao.roomTimeline(ctx, roomId)
+	if beforeId > 0 {
+		model = model.Where("(m.created_at, m.id) < (SELECT created_at, id FROM messages WHERE id = ?)", beforeId)
+	}
+	return model.Order("m.created_at DESC, m.id DESC").Limit(limit).All()
+}
+
+// GetRoomMessagesAfter retrieves up to limit messages of the main timeline of a room newer than
+// a message, oldest first. Thread replies are left out.
+func (dao *MessageDao) GetRoomMessagesAfter(ctx context.Context, roomId, afterId uint, limit int) (gdb.Result, error) {
+	return dao.roomTimeline(ctx, roomId).
+		Where("(m.created_at, m.id) > (SELECT created_at, id FROM messages WHERE id = ?)", afterId).
+		Order("m.created_at ASC, m.id ASC").
+		Limit(limit).
+		All()
+}
+
+// GetRoomMessagesAfterSeq retrieves up to limit messages of a room with a sequence number above
+// afterSeq, in sequence order. Thread replies take sequence numbers too, so they are included;
+// deleted messages are left out.
+func (dao *MessageDao) GetRoomMessagesAfterSeq(ctx context.Context, roomId, afterSeq uint, limit int) (gdb.Result, error) {
+	return Model(ctx, MessageTable).
+		Unscoped().
+		As("m").
+		LeftJoin("users u", "u.id = m.user_id").
+		Where("m.room_id", roomId).
+		Fields(messageWithUserFields).
+		Where("m.seq > ?", afterSeq).
+		Where("m.deleted_at IS NULL").
+		Order("m.seq ASC").
+		Limit(limit).
+		All()
 }
 
-// GetRoomMessagesWithUser retrieves messages with user information for a room
-func (dao *MessageDao) GetRoomMessagesWithUser(ctx context.Context, roomId uint, page, size int) (gdb.Result, int, error) {
+// roomTimeline selects the main timeline of a room with user information.
+// Messages are ordered by created_at with ties broken by id, so cursors are stable.
+func (dao *MessageDao) roomTimeline(ctx context.Context, roomId uint) *gdb.Model {
+	// deleted_at makes gdb hide deleted messages; history keeps them as tombstones
+	return Model(ctx, MessageTable).
+		Unscoped().
+		As("m").
+		LeftJoin("users u", "u.id = m.user_id").
+		Where("m.room_id", roomId).
+		Where("m.parent_id", 0).
+		Fields(messageWithUserFields)
+}
+
+// Search finds text messages matching a full-text query, best match first. Each result has the
+// fields of messageWithUserFields plus a snippet with the matches wrapped in SearchMatchStart and
+// SearchMatchEnd and the name of its room. Deleted messages are left out.
+func (dao *MessageDao) Search(ctx context.Context, filter *MessageSearchFilter, page, size int) (gdb.Result, int, error) {
+	if len(filter.RoomIds) == 0 {
+		return gdb.Result{}, 0, nil
+	}
+
+	// Quote the query so FTS5 operators typed by users are searched for literally
 	model := Model(ctx, MessageTable).
 		As("m").
+		InnerJoin(MessageSearchTable+" f", "f.rowid = m.id").
 		LeftJoin("users u", "u.id = m.user_id").
-		Where("m.room_id", roomId)
-
-	list, total, err := model.Fields(`
-		m.*,
-		u.username,
-		u.nickname,
-		u.avatar
-	`).
-		Order("m.created_at DESC").
+		LeftJoin("chatrooms cr", "cr.id = m.room_id").
+		Where("f."+MessageSearchTable+" MATCH ?", `"`+strings.ReplaceAll(filter.Query, `"`, `""`)+`"`).
+		WhereIn("m.room_id", filter.RoomIds)
+	if filter.UserId > 0 {
+		model = model.Where("m.user_id", filter.UserId)
+	}
+	if filter.From != "" {
+		model = model.WhereGTE("m.created_at", filter.From)
+	}
+	if filter.Until != "" {
+		model = model.WhereLT("m.created_at", filter.Until)
+	}
+
+	return model.Fields(messageWithUserFields+`,
+		snippet(`+MessageSearchTable+`, 0, char(2), char(3), '…', 32) AS snippet,
+		cr.name AS room_name`).
+		Order("f.rank, m.id DESC").
+		Page(page, size).AllAndCount(false)
+}
+
+// GetThreadRepliesWithUser retrieves the replies to a thread root with user information, oldest first
+func (dao *MessageDao) GetThreadRepliesWithUser(ctx context.Context, rootId uint, page, size int) (gdb.Result, int, error) {
+	model := Model(ctx, MessageTable).
+		Unscoped().
+		As("m").
+		LeftJoin("users u", "u.id = m.user_id").
+		Where("m.parent_id", rootId)
+
+	return model.Fields(messageWithUserFields).
+		Order("m.id ASC").
 		Page(page, size).AllAndCount(false)
+}
 
-	return list, total, err
+// GetMessageWithUser retrieves a single message with user information, including deleted ones
+func (dao *MessageDao) GetMessageWithUser(ctx context.Context, id uint) (gdb.Record, error) {
+	return Model(ctx, MessageTable).
+		Unscoped().
+		As("m").
+		LeftJoin("users u", "u.id = m.user_id").
+		Where("m.id", id).
+		Fields(messageWithUserFields).
+		One()
+}
+
+// GetThreadStats returns the number of replies to a thread root and the time of the last one
+func (dao *MessageDao) GetThreadStats(ctx context.Context, rootId uint) (replyCount int, lastReplyAt string, err error) {
+	record, err := Model(ctx, MessageTable).
+		Fields("COUNT(1) AS reply_count, STRFTIME('%Y-%m-%d %H:%M:%S', MAX(created_at)) AS last_reply_at").
+		Where("parent_id", rootId).
+		One()
+	if err != nil {
+		return 0, "", err
+	}
+	return record["reply_count"].Int(), record["last_reply_at"].String(), nil
+}
+
+// ListThreadParticipants returns the IDs of the root author and every user who replied to a thread,
+// leaving out those who are no longer members of its room
+func (dao *MessageDao) ListThreadParticipants(ctx context.Context, rootId uint) ([]uint, error) {
+	values, err := Model(ctx, MessageTable).
+		Unscoped().
+		Fields("DISTINCT user_id").
+		Where("id = ? OR parent_id = ?", rootId, rootId).
+		Where("user_id IN (SELECT ru.user_id FROM room_users ru WHERE ru.room_id = messages.room_id)").
+		Array()
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := make([]uint, 0, len(values))
+	for _, v := range values {
+		userIds = append(userIds, v.Uint())
+	}
+	return userIds, nil
 }
 
 // GetUserMessages retrieves all messages sent by a user
@@ -75,13 +252,131 @@ func (dao *MessageDao) GetUserMessages(ctx context.Context, userId uint, page, s
 
 // DeleteRoomMessages deletes all messages in a room
 func (dao *MessageDao) DeleteRoomMessages(ctx context.Context, roomId uint) error {
-	_, err := Model(ctx, MessageTable).Where("room_id", roomId).Delete()
+	for _, table := range []string{MessageEditTable, MessageReactionTable} {
+		_, err := Model(ctx, table).
+			Where("message_id IN (SELECT id FROM messages WHERE room_id = ?)", roomId).
+			Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := Model(ctx, MessageTable).Unscoped().Where("room_id", roomId).Delete()
+	return err
+}
+
+// ListUnread counts, for every room a user is in, the messages after their read position
+// that were sent by other users, keyed by room ID. Deleted messages and thread replies are not counted.
+func (dao *MessageDao) ListUnread(ctx context.Context, userId uint) (map[uint]RoomUnread, error) {
+	// Unscoped keeps gdb from filtering on m.deleted_at in WHERE, which would drop rooms
+	// without unread messages; the join condition filters deleted messages instead
+	var list []RoomUnread
+	err := Model(ctx, RoomUserTable).
+		As("ru").
+		Unscoped().
+		LeftJoin("messages m", "m.room_id = ru.room_id AND m.id > ru.last_read_message_id AND m.parent_id = 0 AND m.user_id <> ru.user_id AND m.deleted_at IS NULL").
+		Fields("ru.room_id, ru.last_read_message_id, COUNT(m.id) AS unread").
+		Where("ru.user_id", userId).
+		Group("ru.room_id, ru.last_read_message_id").
+		Scan(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	unread := make(map[uint]RoomUnread, len(list))
+	for _, room := range list {
+		unread[room.RoomId] = room
+	}
+	return unread, nil
+}
+
+// GetLastRoomMessage retrieves the most recent message of a room that was not deleted, or nil if it has none
+func (dao *MessageDao) GetLastRoomMessage(ctx context.Context, roomId uint) (*entity.Message, error) {
+	var message *entity.Message
+	err := Model(ctx, MessageTable).Where("room_id", roomId).Order("id DESC").Limit(1).Scan(&message)
+	return message, err
+}
+
+// UpdateContent replaces the content of a message, keeping the previous content in its edit history
+func (dao *MessageDao) UpdateContent(ctx context.Context, message *entity.Message, content string) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		_, err := Model(ctx, MessageEditTable).Data(g.Map{
+			"message_id": message.Id,
+			"content":    message.Content,
+		}).Insert()
+		if err != nil {
+			return err
+		}
+
+		_, err = Model(ctx, MessageTable).Where("id", message.Id).Data(g.Map{
+			"content":   content,
+			"edited_at": time.Now(),
+		}).Update()
+		return err
+	})
+}
+
+// SoftDelete turns a message into a tombstone, erasing its content, attachment metadata,
+// edit history, reactions and notifications. The attachment contents are left to the caller.
+func (dao *MessageDao) SoftDelete(ctx context.Context, id, deletedBy uint) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		for _, table := range []string{MessageEditTable, MessageReactionTable, NotificationTable} {
+			if _, err := Model(ctx, table).Where("message_id", id).Delete(); err != nil {
+				return err
+			}
+		}
+
+		_, err := Model(ctx, AttachmentThumbnailTable).
+			Where("attachment_id IN (SELECT attachment_id FROM messages WHERE id = ?)", id).
+			Delete()
+		if err != nil {
+			return err
+		}
+		_, err = Model(ctx, AttachmentTable).
+			Where("id IN (SELECT attachment_id FROM messages WHERE id = ?)", id).
+			Delete()
+		if err != nil {
+			return err
+		}
+
+		_, err = Model(ctx, MessageTable).Where("id", id).Data(g.Map{
+			"content":       "",
+			"attachment_id": 0,
+			"deleted_at":    time.Now(),
+			"deleted_by":    deletedBy,
+		}).Update()
+		return err
+	})
+}
+
+// GetByNonce retrieves the message a user sent with a nonce, including deleted ones, or nil if there is none
+func (dao *MessageDao) GetByNonce(ctx context.Context, userId uint, nonce string) (*entity.Message, error) {
+	var message *entity.Message
+	err := Model(ctx, MessageTable).Unscoped().Where("user_id", userId).Where("nonce", nonce).Scan(&message)
+	return message, err
+}
+
+// ClearNonce releases the nonce of a message, including a deleted one, so that it can be used again
+func (dao *MessageDao) ClearNonce(ctx context.Context, id uint) error {
+	_, err := Model(ctx, MessageTable).Unscoped().Where("id", id).Data(g.Map{"nonce": ""}).Update()
 	return err
 }
 
-// GetMessageById retrieves a message by its ID
+// IsAttachmentSent reports whether a message referencing an attachment exists
+func (dao *MessageDao) IsAttachmentSent(ctx context.Context, attachmentId uint) (bool, error) {
+	count, err := Model(ctx, MessageTable).Unscoped().Where("attachment_id", attachmentId).Count()
+	return count > 0, err
+}
+
+// ListEdits returns the edit history of a message, oldest first
+func (dao *MessageDao) ListEdits(ctx context.Context, messageId uint) (edits []entity.MessageEdit, err error) {
+	err = Model(ctx, MessageEditTable).Where("message_id", messageId).Order("id ASC").Scan(&edits)
+	return
+}
+
+// GetMessageById retrieves a message by its ID, including deleted ones
 func (dao *MessageDao) GetMessageById(ctx context.Context, id uint) (*entity.Message, error) {
 	var message *entity.Message
-	err := Model(ctx, MessageTable).Where("id", id).Scan(&message)
+	err := Model(ctx, MessageTable).Unscoped().Where("id", id).Scan(&message)
 	return message, err
 }
@@ -0,0 +1,188 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// migrateDatabase upgrades tables created by older versions to the current schema
+func migrateDatabase(ctx context.Context) error {
+	// Global user roles; the seeded admin account becomes a global admin
+	added, err := addColumnIfNotExists(ctx, UserTable, "role", "VARCHAR(20) DEFAULT 'user'")
+	if err != nil {
+		return err
+	}
+	if added {
+		if _, err := g.DB().Exec(ctx, "UPDATE users SET role = 'admin' WHERE username = 'admin'"); err != nil {
+			return err
+		}
+	}
+
+	// Room roles; existing room creators become owners
+	added, err = addColumnIfNotExists(ctx, RoomUserTable, "role", "VARCHAR(20) DEFAULT 'member'")
+	if err != nil {
+		return err
+	}
+	if added {
+		_, err = g.DB().Exec(ctx, `
+			UPDATE room_users SET role = 'owner'
+			WHERE EXISTS (
+				SELECT 1 FROM chatrooms cr
+				WHERE cr.id = room_users.room_id AND cr.creator_id = room_users.user_id
+			)
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Read positions; existing members start with everything read
+	added, err = addColumnIfNotExists(ctx, RoomUserTable, "last_read_message_id", "INTEGER DEFAULT 0")
+	if err != nil {
+		return err
+	}
+	if added {
+		_, err = g.DB().Exec(ctx, `
+			UPDATE room_users SET last_read_message_id = (
+				SELECT IFNULL(MAX(m.id), 0) FROM messages m WHERE m.room_id = room_users.room_id
+			)
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Message edits, tombstones, threads and attachments
+	for column, definition := range map[string]string{
+		"edited_at":     "DATETIME",
+		"deleted_at":    "DATETIME",
+		"deleted_by":    "INTEGER DEFAULT 0",
+		"parent_id":     "INTEGER DEFAULT 0",
+		"attachment_id": "INTEGER DEFAULT 0",
+	} {
+		if _, err = addColumnIfNotExists(ctx, MessageTable, column, definition); err != nil {
+			return err
+		}
+	}
+	_, err = g.DB().Exec(ctx, "CREATE INDEX IF NOT EXISTS idx_messages_parent_id ON messages(parent_id)")
+	if err != nil {
+		return err
+	}
+
+	// Per-room sequence numbers; existing messages are numbered in the order they were stored
+	added, err = addColumnIfNotExists(ctx, MessageTable, "seq", "INTEGER DEFAULT 0")
+	if err != nil {
+		return err
+	}
+	if added {
+		_, err = g.DB().Exec(ctx, `
+			UPDATE messages SET seq = (
+				SELECT COUNT(1) FROM messages m WHERE m.room_id = messages.room_id AND m.id <= messages.id
+			)
+		`)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = g.DB().Exec(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_room_seq ON messages(room_id, seq)")
+	if err != nil {
+		return err
+	}
+
+	// Client nonces identifying repeated sends; messages without one are not indexed
+	if _, err = addColumnIfNotExists(ctx, MessageTable, "nonce", "VARCHAR(64) DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err = g.DB().Exec(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_user_nonce ON messages(user_id, nonce) WHERE nonce != ''")
+	if err != nil {
+		return err
+	}
+
+	// Image dimensions of attachments
+	for _, column := range []string{"width", "height"} {
+		if _, err = addColumnIfNotExists(ctx, AttachmentTable, column, "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// Room types; direct conversations are identified by the unique dm_key of their two members
+	if _, err = addColumnIfNotExists(ctx, ChatRoomTable, "type", "VARCHAR(10) DEFAULT 'group'"); err != nil {
+		return err
+	}
+	if _, err = addColumnIfNotExists(ctx, ChatRoomTable, "dm_key", "VARCHAR(40)"); err != nil {
+		return err
+	}
+	_, err = g.DB().Exec(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS idx_chatrooms_dm_key ON chatrooms(dm_key)")
+	if err != nil {
+		return err
+	}
+
+	// Full-text search index of text messages
+	if err = createMessageSearchIndex(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// messageSearchSchema creates the FTS5 index of text messages and the triggers keeping it in sync
+// with the messages table. The trigram tokenizer also matches inside words, which is needed for
+// Chinese text without spaces.
+const messageSearchSchema = `
+	CREATE VIRTUAL TABLE messages_fts USING fts5(
+		content, content='messages', content_rowid='id', tokenize='trigram'
+	);
+	CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages WHEN new.type = 0 BEGIN
+		INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages WHEN old.type = 0 BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF content ON messages WHEN old.type = 0 BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+	INSERT INTO messages_fts(rowid, content) SELECT id, content FROM messages WHERE type = 0;
+`
+
+// createMessageSearchIndex creates the full-text search index of messages unless it already
+// exists, indexing the messages stored so far
+func createMessageSearchIndex(ctx context.Context) error {
+	exists, err := g.DB().GetValue(ctx, "SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ?", MessageSearchTable)
+	if err != nil || exists.Int() > 0 {
+		return err
+	}
+
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := tx.Exec(messageSearchSchema); err != nil {
+			return err
+		}
+		glog.Info(ctx, "Created message search index")
+		return nil
+	})
+}
+
+// addColumnIfNotExists adds a column to a table unless it already exists.
+// It reports whether the column was added.
+func addColumnIfNotExists(ctx context.Context, table, column, definition string) (bool, error) {
+	columns, err := g.DB().GetAll(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	for _, c := range columns {
+		if c["name"].String() == column {
+			return false, nil
+		}
+	}
+
+	_, err = g.DB().Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return false, err
+	}
+	glog.Info(ctx, "Added column", column, "to table", table)
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"chatroom/internal/model/entity"
+	"context"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ModerationDao handles database operations for room bans, mutes and the moderation log
+type ModerationDao struct{}
+
+// RoomRestrictionTable is the name of the room restriction table
+const RoomRestrictionTable = "room_restrictions"
+
+// ModerationLogTable is the name of the moderation log table
+const ModerationLogTable = "moderation_logs"
+
+// NewModerationDao returns a new ModerationDao instance
+func NewModerationDao() *ModerationDao {
+	return &ModerationDao{}
+}
+
+// SetRestriction stores a restriction, replacing an existing one of the same type
+func (dao *ModerationDao) SetRestriction(ctx context.Context, r *entity.RoomRestriction) error {
+	if err := dao.RemoveRestriction(ctx, r.RoomId, r.UserId, r.Type); err != nil {
+		return err
+	}
+
+	data := g.Map{
+		"room_id":      r.RoomId,
+		"user_id":      r.UserId,
+		"type":         r.Type,
+		"moderator_id": r.ModeratorId,
+		"reason":       r.Reason,
+	}
+	if !r.ExpiresAt.IsZero() {
+		data["expires_at"] = r.ExpiresAt
+	}
+	_, err := Model(ctx, RoomRestrictionTable).Data(data).Insert()
+	return err
+}
+
+// GetRestriction retrieves a user's restriction of the given type in a room
+func (dao *ModerationDao) GetRestriction(ctx context.Context, roomId, userId uint, restrictionType string) (*entity.RoomRestriction, error) {
+	var r *entity.RoomRestriction
+	err := Model(ctx, RoomRestrictionTable).
+		Where("room_id", roomId).
+		Where("user_id", userId).
+		Where("type", restrictionType).
+		Scan(&r)
+	return r, err
+}
+
+// RemoveRestriction lifts a user's restriction of the given type in a room
+func (dao *ModerationDao) RemoveRestriction(ctx context.Context, roomId, userId uint, restrictionType string) error {
+	_, err := Model(ctx, RoomRestrictionTable).
+		Where("room_id", roomId).
+		Where("user_id", userId).
+		Where("type", restrictionType).
+		Delete()
+	return err
+}
+
+// ListRestrictions returns all restrictions in a room, including expired ones
+func (dao *ModerationDao) ListRestrictions(ctx context.Context, roomId uint) (restrictions []entity.RoomRestriction, err error) {
+	err = Model(ctx, RoomRestrictionTable).
+		Where("room_id", roomId).
+		Order("created_at DESC").
+		Scan(&restrictions)
+	return
+}
+
+// AddLog records a moderation action
+func (dao *ModerationDao) AddLog(ctx context.Context, log *entity.ModerationLog) error {
+	data := g.Map{
+		"room_id":        log.RoomId,
+		"moderator_id":   log.ModeratorId,
+		"target_user_id": log.TargetUserId,
+		"action":         log.Action,
+		"reason":         log.Reason,
+	}
+	if !log.ExpiresAt.IsZero() {
+		data["expires_at"] = log.ExpiresAt
+	}
+	_, err := Model(ctx, ModerationLogTable).Data(data).Insert()
+	return err
+}
+
+// ListLogs returns a paginated moderation log of a room, newest first
+func (dao *ModerationDao) ListLogs(ctx context.Context, roomId uint, page, size int) (logs []entity.ModerationLog, total int, err error) {
+	model := Model(ctx, ModerationLogTable).Where("room_id", roomId)
+
+	total, err = model.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = model.Page(page, size).Order("id DESC").Scan(&logs)
+	return logs, total, err
+}
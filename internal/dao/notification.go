@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"chatroom/internal/model/entity"
+	"context"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// NotificationDao handles database operations for user notifications
+type NotificationDao struct{}
+
+// NotificationTable is the name of the notification table
+const NotificationTable = "notifications"
+
+// NewNotificationDao returns a new NotificationDao instance
+func NewNotificationDao() *NotificationDao {
+	return &NotificationDao{}
+}
+
+// Create creates an unread notification and returns its ID
+func (dao *NotificationDao) Create(ctx context.Context, notification *entity.Notification) (uint, error) {
+	result, err := Model(ctx, NotificationTable).Data(g.Map{
+		"user_id":    notification.UserId,
+		"type":       notification.Type,
+		"room_id":    notification.RoomId,
+		"message_id": notification.MessageId,
+		"actor_id":   notification.ActorId,
+		"content":    notification.Content,
+	}).Insert()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return uint(id), err
+}
+
+// List returns a paginated list of a user's notifications, newest first, optionally only the unread ones
+func (dao *NotificationDao) List(ctx context.Context, userId uint, unreadOnly bool, page, size int) (notifications []entity.Notification, total int, err error) {
+	model := Model(ctx, NotificationTable).Where("user_id", userId)
+	if unreadOnly {
+		model = model.Where("is_read", false)
+	}
+
+	total, err = model.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = model.Page(page, size).Order("id DESC").Scan(&notifications)
+	return notifications, total, err
+}
+
+// CountUnread counts the unread notifications of a user
+func (dao *NotificationDao) CountUnread(ctx context.Context, userId uint) (int, error) {
+	return Model(ctx, NotificationTable).Where("user_id", userId).Where("is_read", false).Count()
+}
+
+// MarkRead marks a notification of a user read. It reports whether the notification exists.
+func (dao *NotificationDao) MarkRead(ctx context.Context, userId, id uint) (bool, error) {
+	result, err := Model(ctx, NotificationTable).
+		Where("id", id).
+		Where("user_id", userId).
+		Data("is_read", true).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// MarkAllRead marks all notifications of a user read
+func (dao *NotificationDao) MarkAllRead(ctx context.Context, userId uint) error {
+	_, err := Model(ctx, NotificationTable).
+		Where("user_id", userId).
+		Where("is_read", false).
+		Data("is_read", true).
+		Update()
+	return err
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ReactionDao handles database operations for message reactions
+type ReactionDao struct{}
+
+// MessageReactionTable is the name of the message reaction table
+const MessageReactionTable = "message_reactions"
+
+// ReactionCount is the number of users who reacted to a message with an emoji
+type ReactionCount struct {
+	MessageId uint   `json:"messageId"`
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+}
+
+// NewReactionDao returns a new ReactionDao instance
+func NewReactionDao() *ReactionDao {
+	return &ReactionDao{}
+}
+
+// Add adds a user's reaction to a message. It reports whether the reaction is new.
+func (dao *ReactionDao) Add(ctx context.Context, messageId, userId uint, emoji string) (bool, error) {
+	result, err := Model(ctx, MessageReactionTable).Data(g.Map{
+		"message_id": messageId,
+		"user_id":    userId,
+		"emoji":      emoji,
+	}).InsertIgnore()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Remove removes a user's reaction from a message. It reports whether the reaction existed.
+func (dao *ReactionDao) Remove(ctx context.Context, messageId, userId uint, emoji string) (bool, error) {
+	result, err := Model(ctx, MessageReactionTable).
+		Where("message_id", messageId).
+		Where("user_id", userId).
+		Where("emoji", emoji).
+		Delete()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CountByMessages aggregates the reactions of messages per emoji, in order of first use
+func (dao *ReactionDao) CountByMessages(ctx context.Context, messageIds []uint) (counts []ReactionCount, err error) {
+	if len(messageIds) == 0 {
+		return nil, nil
+	}
+	err = Model(ctx, MessageReactionTable).
+		Fields("message_id, emoji, COUNT(1) AS count").
+		WhereIn("message_id", messageIds).
+		Group("message_id, emoji").
+		Order("MIN(created_at) ASC").
+		Scan(&counts)
+	return
+}
+
+// ListUserEmojis returns the emojis a user reacted with to each of the messages
+func (dao *ReactionDao) ListUserEmojis(ctx context.Context, messageIds []uint, userId uint) (map[uint][]string, error) {
+	emojis := make(map[uint][]string)
+	if len(messageIds) == 0 {
+		return emojis, nil
+	}
+
+	result, err := Model(ctx, MessageReactionTable).
+		Fields("message_id, emoji").
+		WhereIn("message_id", messageIds).
+		Where("user_id", userId).
+		All()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range result {
+		messageId := record["message_id"].Uint()
+		emojis[messageId] = append(emojis[messageId], record["emoji"].String())
+	}
+	return emojis, nil
+}
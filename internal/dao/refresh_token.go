@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RefreshTokenDao handles database operations for refresh tokens
+type RefreshTokenDao struct{}
+
+// RefreshTokenTable is the name of the refresh token table
+const RefreshTokenTable = "refresh_tokens"
+
+// NewRefreshTokenDao returns a new RefreshTokenDao instance
+func NewRefreshTokenDao() *RefreshTokenDao {
+	return &RefreshTokenDao{}
+}
+
+// Create stores a new refresh token
+func (dao *RefreshTokenDao) Create(ctx context.Context, token *entity.RefreshToken) (uint, error) {
+	result, err := Model(ctx, RefreshTokenTable).Data(g.Map{
+		"user_id":    token.UserId,
+		"token_hash": token.TokenHash,
+		"session_id": token.SessionId,
+		"access_jti": token.AccessJti,
+		"expires_at": token.ExpiresAt,
+	}).Insert()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return uint(id), err
+}
+
+// GetByHash retrieves a refresh token by its hash
+func (dao *RefreshTokenDao) GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	var token *entity.RefreshToken
+	err := Model(ctx, RefreshTokenTable).Where("token_hash", tokenHash).Scan(&token)
+	return token, err
+}
+
+// GetByAccessJti retrieves the refresh token issued together with an access token
+func (dao *RefreshTokenDao) GetByAccessJti(ctx context.Context, jti string) (*entity.RefreshToken, error) {
+	var token *entity.RefreshToken
+	err := Model(ctx, RefreshTokenTable).Where("access_jti", jti).Scan(&token)
+	return token, err
+}
+
+// IsAccessTokenActive checks if the access token with the given JTI has not been revoked
+func (dao *RefreshTokenDao) IsAccessTokenActive(ctx context.Context, jti string) (bool, error) {
+	count, err := Model(ctx, RefreshTokenTable).
+		Where("access_jti", jti).
+		Where("revoked", false).
+		Count()
+	return count > 0, err
+}
+
+// Rotate marks a refresh token as replaced by a newer one. It reports false if the token was
+// already revoked, e.g. by a concurrent rotation, in which case nothing is changed.
+func (dao *RefreshTokenDao) Rotate(ctx context.Context, id, replacedBy uint) (bool, error) {
+	result, err := Model(ctx, RefreshTokenTable).
+		Where("id", id).
+		Where("revoked", false).
+		Data(g.Map{
+			"revoked":     true,
+			"replaced_by": replacedBy,
+		}).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeSession revokes all tokens of a login session
+func (dao *RefreshTokenDao) RevokeSession(ctx context.Context, sessionId string) error {
+	_, err := Model(ctx, RefreshTokenTable).
+		Where("session_id", sessionId).
+		Where("revoked", false).
+		Data(g.Map{"revoked": true}).
+		Update()
+	return err
+}
+
+// RevokeUser revokes all tokens of a user
+func (dao *RefreshTokenDao) RevokeUser(ctx context.Context, userId uint) error {
+	_, err := Model(ctx, RefreshTokenTable).
+		Where("user_id", userId).
+		Where("revoked", false).
+		Data(g.Map{"revoked": true}).
+		Update()
+	return err
+}
+
+// DeleteExpired deletes the tokens that expired before the given time, whether revoked or not,
+// and returns how many were deleted
+func (dao *RefreshTokenDao) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := Model(ctx, RefreshTokenTable).WhereLT("expires_at", before).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
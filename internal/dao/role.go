@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"chatroom/internal/consts"
+	"context"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// RoleDao handles database operations for role permissions
+type RoleDao struct{}
+
+// RolePermissionTable is the name of the role-permission table
+const RolePermissionTable = "role_permissions"
+
+// defaultRolePermissions are the permissions every role is granted out of the box.
+// Additional grants can be inserted into the role_permissions table directly.
+var defaultRolePermissions = map[string][]string{
+	consts.RoleAdmin: {
+		consts.PermRoomCreate,
+		consts.PermRoomJoin,
+		consts.PermRoomView,
+		consts.PermRoomDelete,
+		consts.PermRoomPrivate,
+		consts.PermMessageRead,
+		consts.PermMessageSend,
+		consts.PermMemberList,
+		consts.PermMemberSetRole,
+		consts.PermMemberInvite,
+		consts.PermMemberKick,
+		consts.PermMemberBan,
+		consts.PermMemberMute,
+		consts.PermModerationLog,
+		consts.PermMessageDelete,
+		consts.PermStorageView,
+		consts.PermStorageReport,
+	},
+	consts.RoleUser: {
+		consts.PermRoomCreate,
+		consts.PermRoomJoin,
+		consts.PermRoomView,
+	},
+	consts.RoomRoleOwner: {
+		consts.PermRoomDelete,
+		consts.PermMessageRead,
+		consts.PermMessageSend,
+		consts.PermMemberList,
+		consts.PermMemberSetRole,
+		consts.PermMemberInvite,
+		consts.PermMemberKick,
+		consts.PermMemberBan,
+		consts.PermMemberMute,
+		consts.PermModerationLog,
+		consts.PermMessageDelete,
+		consts.PermStorageView,
+	},
+	consts.RoomRoleModerator: {
+		consts.PermMessageRead,
+		consts.PermMessageSend,
+		consts.PermMemberList,
+		consts.PermMemberInvite,
+		consts.PermMemberKick,
+		consts.PermMemberBan,
+		consts.PermMemberMute,
+		consts.PermModerationLog,
+		consts.PermMessageDelete,
+	},
+	consts.RoomRoleMember: {
+		consts.PermMessageRead,
+		consts.PermMessageSend,
+		consts.PermMemberList,
+	},
+}
+
+// NewRoleDao returns a new RoleDao instance
+func NewRoleDao() *RoleDao {
+	return &RoleDao{}
+}
+
+// SeedDefaults inserts the default role permissions that are missing
+func (dao *RoleDao) SeedDefaults(ctx context.Context) error {
+	for role, perms := range defaultRolePermissions {
+		for _, perm := range perms {
+			_, err := Model(ctx, RolePermissionTable).Data(g.Map{
+				"role":       role,
+				"permission": perm,
+			}).InsertIgnore()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HasPermission checks if any of the given roles grants the permission
+func (dao *RoleDao) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	if len(roles) == 0 {
+		return false, nil
+	}
+	count, err := Model(ctx, RolePermissionTable).
+		WhereIn("role", roles).
+		Where("permission", permission).
+		Count()
+	return count > 0, err
+}
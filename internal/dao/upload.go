@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// UploadDao handles database operations for resumable uploads
+type UploadDao struct{}
+
+// UploadTable is the name of the upload table
+const UploadTable = "uploads"
+
+// NewUploadDao returns a new UploadDao instance
+func NewUploadDao() *UploadDao {
+	return &UploadDao{}
+}
+
+// Create stores a new upload
+func (dao *UploadDao) Create(ctx context.Context, upload *entity.Upload) error {
+	_, err := Model(ctx, UploadTable).Data(g.Map{
+		"id":            upload.Id,
+		"user_id":       upload.UserId,
+		"room_id":       upload.RoomId,
+		"name":          upload.Name,
+		"size":          upload.Size,
+		"upload_offset": upload.Offset,
+		"expires_at":    upload.ExpiresAt,
+	}).Insert()
+	return err
+}
+
+// GetByID retrieves an upload by ID
+func (dao *UploadDao) GetByID(ctx context.Context, id string) (*entity.Upload, error) {
+	var upload *entity.Upload
+	err := Model(ctx, UploadTable).Where("id", id).Scan(&upload)
+	return upload, err
+}
+
+// UpdateOffset records the number of bytes received and extends the expiry of an upload
+func (dao *UploadDao) UpdateOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error {
+	_, err := Model(ctx, UploadTable).Where("id", id).Data(g.Map{
+		"upload_offset": offset,
+		"expires_at":    expiresAt,
+	}).Update()
+	return err
+}
+
+// Delete deletes an upload
+func (dao *UploadDao) Delete(ctx context.Context, id string) error {
+	_, err := Model(ctx, UploadTable).Where("id", id).Delete()
+	return err
+}
+
+// SumPendingSize returns the total size of a user's unexpired uploads
+func (dao *UploadDao) SumPendingSize(ctx context.Context, userId uint) (int64, error) {
+	value, err := Model(ctx, UploadTable).
+		Where("user_id", userId).
+		WhereGT("expires_at", time.Now()).
+		Value("IFNULL(SUM(size), 0)")
+	if err != nil {
+		return 0, err
+	}
+	return value.Int64(), nil
+}
+
+// SumPendingSizeByRoom returns the total size of the unexpired uploads to a room
+func (dao *UploadDao) SumPendingSizeByRoom(ctx context.Context, roomId uint) (int64, error) {
+	value, err := Model(ctx, UploadTable).
+		Where("room_id", roomId).
+		WhereGT("expires_at", time.Now()).
+		Value("IFNULL(SUM(size), 0)")
+	if err != nil {
+		return 0, err
+	}
+	return value.Int64(), nil
+}
+
+// SumTotalPendingSize returns the total size of all unexpired uploads
+func (dao *UploadDao) SumTotalPendingSize(ctx context.Context) (int64, error) {
+	value, err := Model(ctx, UploadTable).
+		WhereGT("expires_at", time.Now()).
+		Value("IFNULL(SUM(size), 0)")
+	if err != nil {
+		return 0, err
+	}
+	return value.Int64(), nil
+}
+
+// ListExpiredIds returns the IDs of uploads that expired before a time
+func (dao *UploadDao) ListExpiredIds(ctx context.Context, before time.Time) ([]string, error) {
+	values, err := Model(ctx, UploadTable).WhereLTE("expires_at", before).Array("id")
+	if err != nil {
+		return nil, err
+	}
+	return gconv.Strings(values), nil
+}
+
+// ListIdsByRoom returns the IDs of the uploads to a room
+func (dao *UploadDao) ListIdsByRoom(ctx context.Context, roomId uint) ([]string, error) {
+	values, err := Model(ctx, UploadTable).Where("room_id", roomId).Array("id")
+	if err != nil {
+		return nil, err
+	}
+	return gconv.Strings(values), nil
+}
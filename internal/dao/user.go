@@ -1,24 +1,29 @@
 package dao
 
 import (
+	"chatroom/internal/consts"
 	"chatroom/internal/model/entity"
+	"chatroom/utility/password"
 	"context"
 	"time"
 
-	"github.com/gogf/gf/v2/crypto/gmd5"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 )
 
 // UserDao handles database operations for users
-type UserDao struct{}
+type UserDao struct {
+	hasher password.PasswordHasher
+}
 
 // UserTable is the name of the user table
 const UserTable = "users"
 
 // NewUserDao returns a new UserDao instance
 func NewUserDao() *UserDao {
-	return &UserDao{}
+	return &UserDao{
+		hasher: password.NewFromConfig(context.Background()),
+	}
 }
 
 // GetByID retrieves a user by ID
@@ -41,11 +46,14 @@ func (dao *UserDao) Create(ctx context.Context, user *entity.User) (uint, error)
 		return 0, gerror.New("Password is required")
 	}
 	// Hash the password before storing
-	pwd, err := gmd5.EncryptString(user.Password)
+	pwd, err := dao.hasher.Hash(user.Password)
 	if err != nil {
 		return 0, err
 	}
 	user.Password = pwd
+	if user.Role == "" {
+		user.Role = consts.RoleUser
+	}
 
 	result, err := Model(ctx, UserTable).Data(g.Map{
 		"username": user.Username,
@@ -53,6 +61,7 @@ func (dao *UserDao) Create(ctx context.Context, user *entity.User) (uint, error)
 		"nickname": user.Nickname,
 		"avatar":   user.Avatar,
 		"status":   user.Status,
+		"role":     user.Role,
 	}).Insert()
 	if err != nil {
 		return 0, err
@@ -85,8 +94,9 @@ func (dao *UserDao) UpdateStatus(ctx context.Context, id uint, status int) error
 	return err
 }
 
-// VerifyPassword checks if a password matches the user's stored password
-func (dao *UserDao) VerifyPassword(ctx context.Context, username, password string) (bool, *entity.User, error) {
+// VerifyPassword checks if a password matches the user's stored password.
+// Hashes produced by a legacy or outdated algorithm are upgraded in place on success.
+func (dao *UserDao) VerifyPassword(ctx context.Context, username, pwd string) (bool, *entity.User, error) {
 	user, err := dao.GetByUsername(ctx, username)
 	if err != nil {
 		return false, nil, err
@@ -95,11 +105,29 @@ func (dao *UserDao) VerifyPassword(ctx context.Context, username, password strin
 		return false, nil, nil
 	}
 
-	// Hash the password and compare
-	hashedPassword, err := gmd5.EncryptString(password)
+	ok, err := password.Verify(dao.hasher, user.Password, pwd)
 	if err != nil {
 		return false, nil, err
 	}
+	if !ok {
+		return false, user, nil
+	}
+
+	// Transparently migrate the stored hash to the configured algorithm
+	if password.NeedsRehash(dao.hasher, user.Password) {
+		if err := dao.UpdatePassword(ctx, user.Id, pwd); err != nil {
+			g.Log().Warning(ctx, "Rehash password failed, user ID:", user.Id, err)
+		}
+	}
+
+	return true, user, nil
+}
 
-	return hashedPassword == user.Password, user, nil
+// UpdatePassword hashes and stores a new password for a user
+func (dao *UserDao) UpdatePassword(ctx context.Context, id uint, pwd string) error {
+	hashed, err := dao.hasher.Hash(pwd)
+	if err != nil {
+		return err
+	}
+	return dao.Update(ctx, id, g.Map{"password": hashed})
 }
@@ -38,9 +38,9 @@ func Auth(r *ghttp.Request) {
 		return
 	}
 
-	// Parse and validate token
+	// Parse and validate token, rejecting revoked ones
 	jwtService := service.NewJwtService()
-	claims, err := jwtService.ParseToken(parts[1])
+	claims, err := jwtService.ValidateToken(r.Context(), parts[1])
 	if err != nil {
 		g.Log().Error(r.Context(), "Invalid or expired token:", err)
 		r.Response.WriteJson(ghttp.DefaultHandlerResponse{
@@ -73,8 +73,9 @@ func Auth(r *ghttp.Request) {
 		return
 	}
 
-	// Store user in context
+	// Store user and token claims in context
 	r.SetCtxVar(consts.ContextKeyUser, user)
+	r.SetCtxVar(consts.ContextKeyClaims, claims)
 
 	r.Middleware.Next()
 }
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+// Attachment holds the metadata of an uploaded file whose contents are kept in the storage backend
+type Attachment struct {
+	Id         uint      `json:"id"         description:"Attachment ID"`
+	UserId     uint      `json:"userId"     description:"User who uploaded the file"`
+	RoomId     uint      `json:"roomId"     description:"Room the file was uploaded to"`
+	StorageKey string    `json:"storageKey" description:"Key of the contents in the storage backend"`
+	Name       string    `json:"name"       description:"Original file name"`
+	Size       int64     `json:"size"       description:"Size in bytes"`
+	MimeType   string    `json:"mimeType"   description:"MIME type detected from the contents"`
+	Checksum   string    `json:"checksum"   description:"Hex-encoded SHA-256 of the contents"`
+	Width      int       `json:"width"      description:"Image width in pixels, 0 for other files"`
+	Height     int       `json:"height"     description:"Image height in pixels, 0 for other files"`
+	CreatedAt  time.Time `json:"createdAt"  description:"Upload time"`
+}
+
+// AttachmentThumbnail is a scaled down copy of an image attachment fitting a square bounding box
+type AttachmentThumbnail struct {
+	AttachmentId uint   `json:"attachmentId" description:"Attachment the thumbnail belongs to"`
+	Size         int    `json:"size"         description:"Edge of the bounding box in pixels"`
+	Width        int    `json:"width"        description:"Width in pixels"`
+	Height       int    `json:"height"       description:"Height in pixels"`
+	StorageKey   string `json:"storageKey"   description:"Key of the contents in the storage backend"`
+	MimeType     string `json:"mimeType"     description:"MIME type of the contents"`
+}
@@ -11,6 +11,8 @@ type ChatRoom struct {
 	Description string    `json:"description" description:"Room description"`
 	CreatorId   uint      `json:"creatorId"   description:"ID of user who created the room"`
 	IsPrivate   bool      `json:"isPrivate"   description:"Whether the room is private (invite only)"`
+	Type        string    `json:"type"        description:"Room type: group, direct"`
+	DmKey       string    `json:"dmKey"       description:"Unique key of the two members of a direct conversation"`
 	CreatedAt   time.Time `json:"createdAt"   description:"Created time"`
 	UpdatedAt   time.Time `json:"updatedAt"   description:"Updated time"`
 }
@@ -6,10 +6,38 @@ import (
 
 // Message represents a chat message sent in a room
 type Message struct {
-	Id        uint      `json:"id"        description:"Message ID"`
-	RoomId    uint      `json:"roomId"    description:"Room the message belongs to"`
-	UserId    uint      `json:"userId"    description:"User who sent the message"`
-	Content   string    `json:"content"   description:"Message content"`
-	Type      int       `json:"type"      description:"Message type: 0-text, 1-image, 2-file, 3-system"`
-	CreatedAt time.Time `json:"createdAt" description:"Created time"`
+	Id           uint      `json:"id"           description:"Message ID"`
+	RoomId       uint      `json:"roomId"       description:"Room the message belongs to"`
+	UserId       uint      `json:"userId"       description:"User who sent the message"`
+	Content      string    `json:"content"      description:"Message content"`
+	Type         int       `json:"type"         description:"Message type: 0-text, 1-image, 2-file, 3-system"`
+	ParentId     uint      `json:"parentId"     description:"Root message of the thread this message replies to, 0 for none"`
+	AttachmentId uint      `json:"attachmentId" description:"Attachment of image and file messages, 0 for none"`
+	Seq          uint      `json:"seq"          description:"Position of the message in its room, increasing by one with every stored message"`
+	Nonce        string    `json:"nonce"        description:"Client key identifying repeated sends of the message, unique per sender"`
+	CreatedAt    time.Time `json:"createdAt"    description:"Created time"`
+	EditedAt     time.Time `json:"editedAt"     description:"Last edited time, zero if never edited"`
+	DeletedAt    time.Time `json:"deletedAt"    description:"Deleted time, zero if not deleted"`
+	DeletedBy    uint      `json:"deletedBy"    description:"User who deleted the message"`
+}
+
+// IsDeleted reports whether the message has been deleted and only remains as a tombstone
+func (m *Message) IsDeleted() bool {
+	return !m.DeletedAt.IsZero()
+}
+
+// MessageEdit keeps the content a message had before one of its edits
+type MessageEdit struct {
+	Id        uint      `json:"id"        description:"Edit ID"`
+	MessageId uint      `json:"messageId" description:"Edited message ID"`
+	Content   string    `json:"content"   description:"Content before the edit"`
+	CreatedAt time.Time `json:"createdAt" description:"Edit time"`
+}
+
+// MessageReaction is an emoji reaction of a user to a message
+type MessageReaction struct {
+	MessageId uint      `json:"messageId" description:"Message ID"`
+	UserId    uint      `json:"userId"    description:"User who reacted"`
+	Emoji     string    `json:"emoji"     description:"Reaction emoji"`
+	CreatedAt time.Time `json:"createdAt" description:"Reaction time"`
 }
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// Notification is an entry of a user's notification inbox, e.g. a mention in a chat room
+type Notification struct {
+	Id        uint      `json:"id"        description:"Notification ID"`
+	UserId    uint      `json:"userId"    description:"User the notification is addressed to"`
+	Type      string    `json:"type"      description:"Notification type: mention, here, all"`
+	RoomId    uint      `json:"roomId"    description:"Room the notification comes from"`
+	MessageId uint      `json:"messageId" description:"Message that triggered the notification"`
+	ActorId   uint      `json:"actorId"   description:"User who triggered the notification"`
+	Content   string    `json:"content"   description:"Excerpt of the triggering message"`
+	IsRead    bool      `json:"isRead"    description:"Whether the notification has been read or dismissed"`
+	CreatedAt time.Time `json:"createdAt" description:"Created time"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// RefreshToken represents a persisted refresh token and the access token issued with it
+type RefreshToken struct {
+	Id         uint      `json:"id"         description:"Refresh token ID"`
+	UserId     uint      `json:"userId"     description:"User the token belongs to"`
+	TokenHash  string    `json:"-"          description:"SHA-256 hash of the refresh token"`
+	SessionId  string    `json:"sessionId"  description:"Login session shared by all rotated tokens"`
+	AccessJti  string    `json:"accessJti"  description:"JTI of the access token issued with this refresh token"`
+	Revoked    bool      `json:"revoked"    description:"Whether the token has been revoked or rotated"`
+	ReplacedBy uint      `json:"replacedBy" description:"ID of the token that replaced this one on rotation"`
+	ExpiresAt  time.Time `json:"expiresAt"  description:"Expiration time"`
+	CreatedAt  time.Time `json:"createdAt"  description:"Created time"`
+}
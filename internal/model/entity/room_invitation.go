@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// RoomInvitation represents a direct invitation of a user to a chat room
+type RoomInvitation struct {
+	Id          uint      `json:"id"          description:"Invitation ID"`
+	RoomId      uint      `json:"roomId"      description:"Room the user is invited to"`
+	InviterId   uint      `json:"inviterId"   description:"User who sent the invitation"`
+	InviteeId   uint      `json:"inviteeId"   description:"User who is invited"`
+	Status      int       `json:"status"      description:"Invitation status: 0-pending, 1-accepted, 2-declined"`
+	CreatedAt   time.Time `json:"createdAt"   description:"Created time"`
+	RespondedAt time.Time `json:"respondedAt" description:"Time the invitee accepted or declined"`
+}
+
+// RoomInviteLink represents a shareable link that lets anyone holding it join a chat room
+type RoomInviteLink struct {
+	Id        uint      `json:"id"        description:"Invite link ID"`
+	RoomId    uint      `json:"roomId"    description:"Room the link joins"`
+	CreatorId uint      `json:"creatorId" description:"User who created the link"`
+	Code      string    `json:"code"      description:"Random invite code"`
+	MaxUses   int       `json:"maxUses"   description:"Maximum number of joins, 0 for unlimited"`
+	Uses      int       `json:"uses"      description:"Number of joins so far"`
+	Revoked   bool      `json:"revoked"   description:"Whether the link has been revoked"`
+	ExpiresAt time.Time `json:"expiresAt" description:"Expiration time, zero for never"`
+	CreatedAt time.Time `json:"createdAt" description:"Created time"`
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+)
+
+// RoomRestriction represents an active ban or mute of a user in a chat room
+type RoomRestriction struct {
+	RoomId      uint      `json:"roomId"      description:"Room ID"`
+	UserId      uint      `json:"userId"      description:"Restricted user ID"`
+	Type        string    `json:"type"        description:"Restriction type: ban, mute"`
+	ModeratorId uint      `json:"moderatorId" description:"Moderator who imposed the restriction"`
+	Reason      string    `json:"reason"      description:"Reason given by the moderator"`
+	ExpiresAt   time.Time `json:"expiresAt"   description:"Expiration time, zero for permanent"`
+	CreatedAt   time.Time `json:"createdAt"   description:"Created time"`
+}
+
+// IsActive reports whether the restriction has not expired yet
+func (r *RoomRestriction) IsActive() bool {
+	return r.ExpiresAt.IsZero() || r.ExpiresAt.After(time.Now())
+}
+
+// ModerationLog records a moderation action taken in a chat room
+type ModerationLog struct {
+	Id           uint      `json:"id"           description:"Log ID"`
+	RoomId       uint      `json:"roomId"       description:"Room ID"`
+	ModeratorId  uint      `json:"moderatorId"  description:"Moderator who took the action"`
+	TargetUserId uint      `json:"targetUserId" description:"User the action was taken against"`
+	Action       string    `json:"action"       description:"Action: kick, ban, unban, mute, unmute"`
+	Reason       string    `json:"reason"       description:"Reason given by the moderator"`
+	ExpiresAt    time.Time `json:"expiresAt"    description:"Expiration of a ban or mute, zero for permanent"`
+	CreatedAt    time.Time `json:"createdAt"    description:"Created time"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"time"
+)
+
+// RoomUser represents a user's membership in a chat room
+type RoomUser struct {
+	RoomId            uint      `json:"roomId"            description:"Room ID"`
+	UserId            uint      `json:"userId"            description:"User ID"`
+	Role              string    `json:"role"              description:"Room role: owner, moderator, member"`
+	LastReadMessageId uint      `json:"lastReadMessageId" description:"ID of the last message the user has read"`
+	JoinedAt          time.Time `json:"joinedAt"          description:"Joined time"`
+}
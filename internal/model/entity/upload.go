@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// Upload is a resumable upload in progress; the received bytes are kept in a partial file
+type Upload struct {
+	Id        string    `json:"id"        description:"Upload ID"`
+	UserId    uint      `json:"userId"    description:"User uploading the file"`
+	RoomId    uint      `json:"roomId"    description:"Room the file is sent to once complete"`
+	Name      string    `json:"name"      description:"Original file name"`
+	Size      int64     `json:"size"      description:"Total size in bytes"`
+	Offset    int64     `json:"offset"    orm:"upload_offset" description:"Number of bytes received"`
+	ExpiresAt time.Time `json:"expiresAt" description:"Time after which the upload is discarded"`
+	CreatedAt time.Time `json:"createdAt" description:"Created time"`
+}
@@ -12,6 +12,7 @@ type User struct {
 	Nickname  string    `json:"nickname"  description:"Display name"`
 	Avatar    string    `json:"avatar"    description:"User avatar URL"`
 	Status    int       `json:"status"    description:"User status: 0-offline, 1-online"`
+	Role      string    `json:"role"      description:"Global role: admin, user"`
 	LastLogin time.Time `json:"lastLogin" description:"Last login time"`
 	CreatedAt time.Time `json:"createdAt" description:"Created time"`
 	UpdatedAt time.Time `json:"updatedAt" description:"Updated time"`
@@ -8,23 +8,31 @@ import (
 	"context"
 	"time"
 
+	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
 )
 
 // ChatRoomService handles chat room business logic
 type ChatRoomService struct {
-	roomDao *dao.ChatRoomDao
+	roomDao     *dao.ChatRoomDao
+	permService *PermissionService
 }
 
 // NewChatRoomService creates a new ChatRoomService instance
 func NewChatRoomService() *ChatRoomService {
 	return &ChatRoomService{
-		roomDao: dao.NewChatRoomDao(),
+		roomDao:     dao.NewChatRoomDao(),
+		permService: NewPermissionService(),
 	}
 }
 
 // Create creates a new chat room
 func (s *ChatRoomService) Create(ctx context.Context, userId uint, req *chatroom.CreateReq) (*chatroom.CreateRes, error) {
+	if err := s.permService.Check(ctx, userId, 0, consts.PermRoomCreate); err != nil {
+		return nil, err
+	}
+
 	room := &entity.ChatRoom{
 		Name:        req.Name,
 		Description: req.Description,
@@ -46,8 +54,18 @@ func (s *ChatRoomService) Create(ctx context.Context, userId uint, req *chatroom
 }
 
 // List returns a paginated list of chat rooms
-func (s *ChatRoomService) List(ctx context.Context, req *chatroom.ListReq) (*chatroom.ListRes, error) {
-	rooms, total, err := s.roomDao.List(ctx, req.Page, req.Size)
+func (s *ChatRoomService) List(ctx context.Context, userId uint, req *chatroom.ListReq) (*chatroom.ListRes, error) {
+	if err := s.permService.Check(ctx, userId, 0, consts.PermRoomView); err != nil {
+		return nil, err
+	}
+
+	// Private rooms are hidden from non-members
+	includePrivate, err := s.permService.Can(ctx, userId, 0, consts.PermRoomPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, total, err := s.roomDao.List(ctx, userId, includePrivate, req.Page, req.Size)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +97,11 @@ func (s *ChatRoomService) List(ctx context.Context, req *chatroom.ListReq) (*cha
 }
 
 // Detail returns details of a chat room
-func (s *ChatRoomService) Detail(ctx context.Context, req *chatroom.DetailReq) (*chatroom.DetailRes, error) {
+func (s *ChatRoomService) Detail(ctx context.Context, userId uint, req *chatroom.DetailReq) (*chatroom.DetailRes, error) {
+	if err := s.permService.Check(ctx, userId, 0, consts.PermRoomView); err != nil {
+		return nil, err
+	}
+
 	room, err := s.roomDao.GetByID(ctx, req.Id)
 	if err != nil {
 		return nil, err
@@ -88,6 +110,15 @@ func (s *ChatRoomService) Detail(ctx context.Context, req *chatroom.DetailReq) (
 		return nil, gerror.New("Chat room not found")
 	}
 
+	// Private rooms look nonexistent to non-members
+	visible, err := s.canSeeRoom(ctx, userId, room)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, gerror.New("Chat room not found")
+	}
+
 	userCount, err := s.roomDao.GetUserCount(ctx, room.Id)
 	if err != nil {
 		return nil, err
@@ -106,12 +137,16 @@ func (s *ChatRoomService) Detail(ctx context.Context, req *chatroom.DetailReq) (
 
 // Join lets a user join a chat room
 func (s *ChatRoomService) Join(ctx context.Context, userId uint, req *chatroom.JoinReq) (*chatroom.JoinRes, error) {
+	if err := s.permService.Check(ctx, userId, 0, consts.PermRoomJoin); err != nil {
+		return nil, err
+	}
+
 	// Check if room exists
 	room, err := s.roomDao.GetByID(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	if room == nil {
+	if room == nil || room.Type == consts.RoomTypeDirect {
 		return nil, gerror.New("Chat room not found")
 	}
 
@@ -124,8 +159,21 @@ func (s *ChatRoomService) Join(ctx context.Context, userId uint, req *chatroom.J
 		return &chatroom.JoinRes{Success: true}, nil
 	}
 
-	// Add user to room
-	err = s.roomDao.AddUser(ctx, req.Id, userId)
+	// Banned users cannot rejoin until the ban expires or is lifted
+	if err := s.checkBan(ctx, userId, req.Id); err != nil {
+		return nil, err
+	}
+
+	// Private rooms require an invitation or a valid invite link, which is only
+	// consumed if the user is actually added to the room
+	err = g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if room.IsPrivate {
+			if err := s.checkPrivateAccess(ctx, userId, room.Id, req.Code); err != nil {
+				return err
+			}
+		}
+		return s.roomDao.AddUser(ctx, req.Id, userId)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -139,6 +187,7 @@ func (s *ChatRoomService) Join(ctx context.Context, userId uint, req *chatroom.J
 	// Broadcast system message about new user
 	wsManager := GetWebSocketManager()
 	wsManager.broadcastToRoom(req.Id, WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
 		Type:      consts.MessageTypeSystem,
 		Content:   user.Nickname + " 加入了聊天室",
 		Timestamp: time.Now().Format(time.RFC3339),
@@ -166,6 +215,9 @@ func (s *ChatRoomService) Leave(ctx context.Context, userId uint, req *chatroom.
 	if !isInRoom {
 		return &chatroom.LeaveRes{Success: true}, nil
 	}
+	if room.Type == consts.RoomTypeDirect {
+		return nil, gerror.New("Direct conversations cannot be left")
+	}
 
 	// Get user info for system message before removing
 	user, err := dao.NewUserDao().GetByID(ctx, userId)
@@ -179,9 +231,13 @@ func (s *ChatRoomService) Leave(ctx context.Context, userId uint, req *chatroom.
 		return nil, err
 	}
 
-	// Broadcast system message about user leaving
+	// Unsubscribe the user's live connections from the room
 	wsManager := GetWebSocketManager()
+	wsManager.UnsubscribeRoomUser(req.Id, userId, consts.ErrNotInRoom)
+
+	// Broadcast system message about user leaving
 	wsManager.broadcastToRoom(req.Id, WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
 		Type:      consts.MessageTypeSystem,
 		Content:   user.Nickname + " 离开了聊天室",
 		Timestamp: time.Now().Format(time.RFC3339),
@@ -190,9 +246,9 @@ func (s *ChatRoomService) Leave(ctx context.Context, userId uint, req *chatroom.
 	return &chatroom.LeaveRes{Success: true}, nil
 }
 
-// Delete deletes a chat room if the user is the creator
+// Delete deletes a chat room if the user is its owner or a global admin
 func (s *ChatRoomService) Delete(ctx context.Context, userId uint, req *chatroom.DeleteReq) (*chatroom.DeleteRes, error) {
-	// Check if room exists and user is the creator
+	// Check if room exists and user may delete it
 	room, err := s.roomDao.GetByID(ctx, req.Id)
 	if err != nil {
 		return nil, err
@@ -200,19 +256,29 @@ func (s *ChatRoomService) Delete(ctx context.Context, userId uint, req *chatroom
 	if room == nil {
 		return nil, gerror.New("Chat room not found")
 	}
-	if room.CreatorId != userId {
-		return nil, gerror.New("Only the room creator can delete the room")
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermRoomDelete); err != nil {
+		return nil, err
 	}
 
 	// Broadcast system message about room deletion
 	wsManager := GetWebSocketManager()
 	wsManager.broadcastToRoom(req.Id, WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
 		Type:      consts.MessageTypeSystem,
 		Content:   "聊天室已被管理员删除",
 		Timestamp: time.Now().Format(time.RFC3339),
 	})
+	wsManager.UnsubscribeRoom(req.Id, "Chat room has been deleted")
 
-	// Delete the room and all associated data
+	// Delete the room and all associated data; attachment contents go last, once nothing references them
+	storageKeys, err := dao.NewAttachmentDao().ListStorageKeysByRoom(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	uploadIds, err := dao.NewUploadDao().ListIdsByRoom(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
 	messageDao := dao.NewMessageDao()
 	if err := messageDao.DeleteRoomMessages(ctx, req.Id); err != nil {
 		return nil, err
@@ -221,6 +287,8 @@ func (s *ChatRoomService) Delete(ctx context.Context, userId uint, req *chatroom
 	if err := s.roomDao.Delete(ctx, req.Id); err != nil {
 		return nil, err
 	}
+	deleteStoredObjects(ctx, storageKeys...)
+	deletePartialFiles(ctx, uploadIds...)
 
 	return &chatroom.DeleteRes{Success: true}, nil
 }
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// canSeeRoom reports whether a user may see a room; private rooms are only visible to members
+// and direct conversations only to their two users
+func (s *ChatRoomService) canSeeRoom(ctx context.Context, userId uint, room *entity.ChatRoom) (bool, error) {
+	if !room.IsPrivate {
+		return true, nil
+	}
+	isInRoom, err := s.roomDao.IsUserInRoom(ctx, room.Id, userId)
+	if err != nil || isInRoom || room.Type == consts.RoomTypeDirect {
+		return isInRoom, err
+	}
+	return s.permService.Can(ctx, userId, 0, consts.PermRoomPrivate)
+}
+
+// checkPrivateAccess checks that a user may join a private room, consuming
+// their pending invitation or one use of the given invite link. Join runs it in
+// the transaction that adds the user so a failed join does not burn the link.
+func (s *ChatRoomService) checkPrivateAccess(ctx context.Context, userId, roomId uint, code string) error {
+	ok, err := s.permService.Can(ctx, userId, 0, consts.PermRoomPrivate)
+	if err != nil || ok {
+		return err
+	}
+
+	// A pending invitation is accepted by joining
+	invitationDao := dao.NewInvitationDao()
+	invitation, err := invitationDao.GetPendingInvitation(ctx, roomId, userId)
+	if err != nil {
+		return err
+	}
+	if invitation != nil {
+		return invitationDao.SetInvitationStatus(ctx, invitation.Id, consts.InvitationStatusAccepted)
+	}
+
+	if code == "" {
+		return gerror.New("This chat room is private and requires an invitation")
+	}
+
+	link, err := invitationDao.GetLinkByCode(ctx, code)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.RoomId != roomId || link.Revoked ||
+		(!link.ExpiresAt.IsZero() && link.ExpiresAt.Before(time.Now())) ||
+		(link.MaxUses > 0 && link.Uses >= link.MaxUses) {
+		return gerror.New("Invalid or expired invite link")
+	}
+
+	used, err := invitationDao.UseLink(ctx, link.Id, link.Uses)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return gerror.New("Invite link was used concurrently, please try again")
+	}
+	return nil
+}
+
+// Invite invites a user to a chat room by username
+func (s *ChatRoomService) Invite(ctx context.Context, userId uint, req *chatroom.InviteReq) (*chatroom.InviteRes, error) {
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermMemberInvite); err != nil {
+		return nil, err
+	}
+
+	// Direct conversations stay between their two users
+	room, err := s.roomDao.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil || room.Type == consts.RoomTypeDirect {
+		return nil, gerror.New("Chat room not found")
+	}
+
+	invitee, err := dao.NewUserDao().GetByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if invitee == nil {
+		return nil, gerror.New("User not found")
+	}
+
+	isInRoom, err := s.roomDao.IsUserInRoom(ctx, req.Id, invitee.Id)
+	if err != nil {
+		return nil, err
+	}
+	if isInRoom {
+		return nil, gerror.New("User is already in the chat room")
+	}
+
+	// Inviting twice keeps the existing invitation
+	invitationDao := dao.NewInvitationDao()
+	invitation, err := invitationDao.GetPendingInvitation(ctx, req.Id, invitee.Id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation != nil {
+		return &chatroom.InviteRes{InvitationId: invitation.Id}, nil
+	}
+
+	id, err := invitationDao.CreateInvitation(ctx, &entity.RoomInvitation{
+		RoomId:    req.Id,
+		InviterId: userId,
+		InviteeId: invitee.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.InviteRes{InvitationId: id}, nil
+}
+
+// ListInvitations returns the pending invitations of a user
+func (s *ChatRoomService) ListInvitations(ctx context.Context, userId uint) (*chatroom.InvitationListRes, error) {
+	invitations, err := dao.NewInvitationDao().ListPendingInvitations(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	userDao := dao.NewUserDao()
+	list := make([]chatroom.Invitation, 0, len(invitations))
+	for _, inv := range invitations {
+		room, err := s.roomDao.GetByID(ctx, inv.RoomId)
+		if err != nil {
+			return nil, err
+		}
+		inviter, err := userDao.GetByID(ctx, inv.InviterId)
+		if err != nil {
+			return nil, err
+		}
+		if room == nil || inviter == nil {
+			continue
+		}
+
+		list = append(list, chatroom.Invitation{
+			Id:              inv.Id,
+			RoomId:          inv.RoomId,
+			RoomName:        room.Name,
+			InviterId:       inv.InviterId,
+			InviterNickname: inviter.Nickname,
+			CreatedAt:       inv.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return &chatroom.InvitationListRes{List: list}, nil
+}
+
+// AcceptInvitation joins the room of a pending invitation
+func (s *ChatRoomService) AcceptInvitation(ctx context.Context, userId uint, req *chatroom.AcceptInvitationReq) (*chatroom.AcceptInvitationRes, error) {
+	invitation, err := s.getPendingInvitation(ctx, userId, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Join accepts the pending invitation of a private room; mark it for public rooms too
+	if _, err := s.Join(ctx, userId, &chatroom.JoinReq{Id: invitation.RoomId}); err != nil {
+		return nil, err
+	}
+	err = dao.NewInvitationDao().SetInvitationStatus(ctx, invitation.Id, consts.InvitationStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.AcceptInvitationRes{RoomId: invitation.RoomId}, nil
+}
+
+// DeclineInvitation declines a pending invitation
+func (s *ChatRoomService) DeclineInvitation(ctx context.Context, userId uint, req *chatroom.DeclineInvitationReq) (*chatroom.DeclineInvitationRes, error) {
+	invitation, err := s.getPendingInvitation(ctx, userId, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dao.NewInvitationDao().SetInvitationStatus(ctx, invitation.Id, consts.InvitationStatusDeclined)
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.DeclineInvitationRes{Success: true}, nil
+}
+
+// getPendingInvitation retrieves a pending invitation addressed to the user
+func (s *ChatRoomService) getPendingInvitation(ctx context.Context, userId, id uint) (*entity.RoomInvitation, error) {
+	invitation, err := dao.NewInvitationDao().GetInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.InviteeId != userId {
+		return nil, gerror.New("Invitation not found")
+	}
+	if invitation.Status != consts.InvitationStatusPending {
+		return nil, gerror.New("Invitation has already been answered")
+	}
+	return invitation, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/grand"
+)
+
+// inviteCodeLength is the length of generated invite link codes
+const inviteCodeLength = 16
+
+// CreateInviteLink creates a shareable invite link for a chat room
+func (s *ChatRoomService) CreateInviteLink(ctx context.Context, userId uint, req *chatroom.CreateInviteLinkReq) (*chatroom.CreateInviteLinkRes, error) {
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermMemberInvite); err != nil {
+		return nil, err
+	}
+
+	link := &entity.RoomInviteLink{
+		RoomId:    req.Id,
+		CreatorId: userId,
+		Code:      grand.S(inviteCodeLength),
+		MaxUses:   req.MaxUses,
+	}
+	if req.ExpiresIn > 0 {
+		link.ExpiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+
+	if _, err := dao.NewInvitationDao().CreateLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return &chatroom.CreateInviteLinkRes{InviteLink: toInviteLink(link)}, nil
+}
+
+// ListInviteLinks returns the active invite links of a chat room
+func (s *ChatRoomService) ListInviteLinks(ctx context.Context, userId uint, req *chatroom.InviteLinkListReq) (*chatroom.InviteLinkListRes, error) {
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermMemberInvite); err != nil {
+		return nil, err
+	}
+
+	links, err := dao.NewInvitationDao().ListLinks(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]chatroom.InviteLink, 0, len(links))
+	for i := range links {
+		list = append(list, toInviteLink(&links[i]))
+	}
+	return &chatroom.InviteLinkListRes{List: list}, nil
+}
+
+// RevokeInviteLink revokes an invite link so it can no longer be used to join
+func (s *ChatRoomService) RevokeInviteLink(ctx context.Context, userId uint, req *chatroom.RevokeInviteLinkReq) (*chatroom.RevokeInviteLinkRes, error) {
+	invitationDao := dao.NewInvitationDao()
+	link, err := invitationDao.GetLinkByCode(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, gerror.New("Invite link not found")
+	}
+	if err := s.permService.Check(ctx, userId, link.RoomId, consts.PermMemberInvite); err != nil {
+		return nil, err
+	}
+
+	if err := invitationDao.RevokeLink(ctx, link.Id); err != nil {
+		return nil, err
+	}
+	return &chatroom.RevokeInviteLinkRes{Success: true}, nil
+}
+
+// toInviteLink converts an invite link entity to its response format
+func toInviteLink(link *entity.RoomInviteLink) chatroom.InviteLink {
+	res := chatroom.InviteLink{
+		Code:    link.Code,
+		RoomId:  link.RoomId,
+		MaxUses: link.MaxUses,
+		Uses:    link.Uses,
+	}
+	if !link.ExpiresAt.IsZero() {
+		res.ExpiresAt = link.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	return res
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// Kick removes a member from a chat room and drops their live connections
+func (s *ChatRoomService) Kick(ctx context.Context, userId uint, req *chatroom.KickReq) (*chatroom.KickRes, error) {
+	target, err := s.prepareModeration(ctx, userId, req.Id, req.UserId, consts.PermMemberKick)
+	if err != nil {
+		return nil, err
+	}
+
+	isInRoom, err := s.roomDao.IsUserInRoom(ctx, req.Id, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if !isInRoom {
+		return nil, gerror.New(consts.ErrNotInRoom)
+	}
+
+	if err := s.roomDao.RemoveUser(ctx, req.Id, req.UserId); err != nil {
+		return nil, err
+	}
+	GetWebSocketManager().UnsubscribeRoomUser(req.Id, req.UserId, "You have been kicked from this chat room")
+
+	err = s.recordModeration(ctx, &entity.ModerationLog{
+		RoomId:       req.Id,
+		ModeratorId:  userId,
+		TargetUserId: req.UserId,
+		Action:       consts.ModerationKick,
+		Reason:       req.Reason,
+	}, fmt.Sprintf("%s 被移出了聊天室", target.Nickname))
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.KickRes{Success: true}, nil
+}
+
+// Ban bans a user from a chat room for a duration or permanently, removing them if they are a member
+func (s *ChatRoomService) Ban(ctx context.Context, userId uint, req *chatroom.BanReq) (*chatroom.BanRes, error) {
+	target, err := s.prepareModeration(ctx, userId, req.Id, req.UserId, consts.PermMemberBan)
+	if err != nil {
+		return nil, err
+	}
+
+	restriction := &entity.RoomRestriction{
+		RoomId:      req.Id,
+		UserId:      req.UserId,
+		Type:        consts.RestrictionBan,
+		ModeratorId: userId,
+		Reason:      req.Reason,
+	}
+	if req.Duration > 0 {
+		restriction.ExpiresAt = time.Now().Add(time.Duration(req.Duration) * time.Second)
+	}
+	if err := dao.NewModerationDao().SetRestriction(ctx, restriction); err != nil {
+		return nil, err
+	}
+
+	// Banned users lose their membership, so rejoining is blocked by Join
+	if err := s.roomDao.RemoveUser(ctx, req.Id, req.UserId); err != nil {
+		return nil, err
+	}
+	GetWebSocketManager().UnsubscribeRoomUser(req.Id, req.UserId, consts.ErrBanned)
+
+	err = s.recordModeration(ctx, &entity.ModerationLog{
+		RoomId:       req.Id,
+		ModeratorId:  userId,
+		TargetUserId: req.UserId,
+		Action:       consts.ModerationBan,
+		Reason:       req.Reason,
+		ExpiresAt:    restriction.ExpiresAt,
+	}, fmt.Sprintf("%s 被封禁%s", target.Nickname, durationText(req.Duration)))
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.BanRes{Success: true}, nil
+}
+
+// Unban lifts a user's ban from a chat room
+func (s *ChatRoomService) Unban(ctx context.Context, userId uint, req *chatroom.UnbanReq) (*chatroom.UnbanRes, error) {
+	target, err := s.prepareModeration(ctx, userId, req.Id, req.UserId, consts.PermMemberBan)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.NewModerationDao().RemoveRestriction(ctx, req.Id, req.UserId, consts.RestrictionBan); err != nil {
+		return nil, err
+	}
+
+	err = s.recordModeration(ctx, &entity.ModerationLog{
+		RoomId:       req.Id,
+		ModeratorId:  userId,
+		TargetUserId: req.UserId,
+		Action:       consts.ModerationUnban,
+	}, fmt.Sprintf("%s 已被解除封禁", target.Nickname))
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.UnbanRes{Success: true}, nil
+}
+
+// Mute stops a member from sending messages to a chat room for a duration or permanently
+func (s *ChatRoomService) Mute(ctx context.Context, userId uint, req *chatroom.MuteReq) (*chatroom.MuteRes, error) {
+	target, err := s.prepareModeration(ctx, userId, req.Id, req.UserId, consts.PermMemberMute)
+	if err != nil {
+		return nil, err
+	}
+
+	isInRoom, err := s.roomDao.IsUserInRoom(ctx, req.Id, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if !isInRoom {
+		return nil, gerror.New(consts.ErrNotInRoom)
+	}
+
+	restriction := &entity.RoomRestriction{
+		RoomId:      req.Id,
+		UserId:      req.UserId,
+		Type:        consts.RestrictionMute,
+		ModeratorId: userId,
+		Reason:      req.Reason,
+	}
+	if req.Duration > 0 {
+		restriction.ExpiresAt = time.Now().Add(time.Duration(req.Duration) * time.Second)
+	}
+	if err := dao.NewModerationDao().SetRestriction(ctx, restriction); err != nil {
+		return nil, err
+	}
+
+	err = s.recordModeration(ctx, &entity.ModerationLog{
+		RoomId:       req.Id,
+		ModeratorId:  userId,
+		TargetUserId: req.UserId,
+		Action:       consts.ModerationMute,
+		Reason:       req.Reason,
+		ExpiresAt:    restriction.ExpiresAt,
+	}, fmt.Sprintf("%s 被禁言%s", target.Nickname, durationText(req.Duration)))
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.MuteRes{Success: true}, nil
+}
+
+// Unmute lifts a member's mute in a chat room
+func (s *ChatRoomService) Unmute(ctx context.Context, userId uint, req *chatroom.UnmuteReq) (*chatroom.UnmuteRes, error) {
+	target, err := s.prepareModeration(ctx, userId, req.Id, req.UserId, consts.PermMemberMute)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.NewModerationDao().RemoveRestriction(ctx, req.Id, req.UserId, consts.RestrictionMute); err != nil {
+		return nil, err
+	}
+
+	err = s.recordModeration(ctx, &entity.ModerationLog{
+		RoomId:       req.Id,
+		ModeratorId:  userId,
+		TargetUserId: req.UserId,
+		Action:       consts.ModerationUnmute,
+	}, fmt.Sprintf("%s 已被解除禁言", target.Nickname))
+	if err != nil {
+		return nil, err
+	}
+	return &chatroom.UnmuteRes{Success: true}, nil
+}
+
+// ListRestrictions returns the active bans and mutes of a chat room
+func (s *ChatRoomService) ListRestrictions(ctx context.Context, userId uint, req *chatroom.RestrictionListReq) (*chatroom.RestrictionListRes, error) {
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermModerationLog); err != nil {
+		return nil, err
+	}
+
+	restrictions, err := dao.NewModerationDao().ListRestrictions(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	userDao := dao.NewUserDao()
+	list := make([]chatroom.Restriction, 0, len(restrictions))
+	for i := range restrictions {
+		r := &restrictions[i]
+		if !r.IsActive() {
+			continue
+		}
+		user, err := userDao.GetByID(ctx, r.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			continue
+		}
+
+		list = append(list, chatroom.Restriction{
+			UserId:      r.UserId,
+			Nickname:    user.Nickname,
+			Type:        r.Type,
+			ModeratorId: r.ModeratorId,
+			Reason:      r.Reason,
+			ExpiresAt:   formatOptionalTime(r.ExpiresAt),
+			CreatedAt:   r.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return &chatroom.RestrictionListRes{List: list}, nil
+}
+
+// ModerationLog returns the moderation log of a chat room
+func (s *ChatRoomService) ModerationLog(ctx context.Context, userId uint, req *chatroom.ModerationLogReq) (*chatroom.ModerationLogRes, error) {
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermModerationLog); err != nil {
+		return nil, err
+	}
+
+	logs, total, err := dao.NewModerationDao().ListLogs(ctx, req.Id, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]chatroom.ModerationLog, 0, len(logs))
+	for _, l := range logs {
+		list = append(list, chatroom.ModerationLog{
+			Id:           l.Id,
+			ModeratorId:  l.ModeratorId,
+			TargetUserId: l.TargetUserId,
+			Action:       l.Action,
+			Reason:       l.Reason,
+			ExpiresAt:    formatOptionalTime(l.ExpiresAt),
+			CreatedAt:    l.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return &chatroom.ModerationLogRes{
+		List:  list,
+		Total: total,
+		Page:  req.Page,
+		Size:  req.Size,
+	}, nil
+}
+
+// checkBan returns an error if the user is currently banned from the room
+func (s *ChatRoomService) checkBan(ctx context.Context, userId, roomId uint) error {
+	ban, err := dao.NewModerationDao().GetRestriction(ctx, roomId, userId, consts.RestrictionBan)
+	if err != nil {
+		return err
+	}
+	if ban != nil && ban.IsActive() {
+		return gerror.New(consts.ErrBanned)
+	}
+	return nil
+}
+
+// prepareModeration checks that the room exists and the actor may moderate the target, and returns the target user
+func (s *ChatRoomService) prepareModeration(ctx context.Context, userId, roomId, targetId uint, permission string) (*entity.User, error) {
+	room, err := s.roomDao.GetByID(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, gerror.New("Chat room not found")
+	}
+
+	target, err := dao.NewUserDao().GetByID(ctx, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, gerror.New("User not found")
+	}
+
+	if err := s.permService.CheckModerate(ctx, userId, targetId, roomId, permission); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// recordModeration writes a moderation log entry and broadcasts the action to the room
+func (s *ChatRoomService) recordModeration(ctx context.Context, log *entity.ModerationLog, content string) error {
+	if err := dao.NewModerationDao().AddLog(ctx, log); err != nil {
+		return err
+	}
+
+	if moderator, err := dao.NewUserDao().GetByID(ctx, log.ModeratorId); err == nil && moderator != nil {
+		content += fmt.Sprintf("（操作人：%s）", moderator.Nickname)
+	}
+	if log.Reason != "" {
+		content += fmt.Sprintf("，原因：%s", log.Reason)
+	}
+
+	GetWebSocketManager().broadcastToRoom(log.RoomId, WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
+		Type:      consts.MessageTypeSystem,
+		Content:   content,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// durationText describes a ban or mute duration for system messages
+func durationText(seconds int) string {
+	if seconds <= 0 {
+		return "（永久）"
+	}
+	return fmt.Sprintf("（%s）", (time.Duration(seconds) * time.Second).String())
+}
+
+// formatOptionalTime formats a time, returning an empty string for the zero time
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"chatroom/api/chatroom"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// SetRole promotes a room member to moderator or demotes them back to member
+func (s *ChatRoomService) SetRole(ctx context.Context, userId uint, req *chatroom.SetRoleReq) (*chatroom.SetRoleRes, error) {
+	// Check if room exists and user may manage roles
+	room, err := s.roomDao.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, gerror.New("Chat room not found")
+	}
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermMemberSetRole); err != nil {
+		return nil, err
+	}
+
+	// Check the target member's current role
+	currentRole, err := s.roomDao.GetMemberRole(ctx, req.Id, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if currentRole == "" {
+		return nil, gerror.New(consts.ErrNotInRoom)
+	}
+	if currentRole == consts.RoomRoleOwner {
+		return nil, gerror.New("The room owner's role cannot be changed")
+	}
+	if currentRole == req.Role {
+		return &chatroom.SetRoleRes{Success: true}, nil
+	}
+
+	if err := s.roomDao.SetMemberRole(ctx, req.Id, req.UserId, req.Role); err != nil {
+		return nil, err
+	}
+
+	// Get user info for system message
+	user, err := dao.NewUserDao().GetByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Broadcast system message about the role change
+	content := fmt.Sprintf("%s 被设为管理员", user.Nickname)
+	if req.Role == consts.RoomRoleMember {
+		content = fmt.Sprintf("%s 不再是管理员", user.Nickname)
+	}
+	wsManager := GetWebSocketManager()
+	wsManager.broadcastToRoom(req.Id, WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
+		Type:      consts.MessageTypeSystem,
+		Content:   content,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+
+	return &chatroom.SetRoleRes{Success: true}, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"chatroom/internal/consts"
+	"chatroom/internal/dao"
 	"chatroom/internal/model/entity"
 	"context"
 	"fmt"
@@ -13,32 +14,44 @@ import (
 
 // JwtService handles JWT token generation and validation
 type JwtService struct {
-	secretKey []byte
+	secretKey     []byte
+	accessExpire  time.Duration
+	refreshExpire time.Duration
+	tokenDao      *dao.RefreshTokenDao
 }
 
 // JwtClaims represents the custom JWT claims
 type JwtClaims struct {
-	UserId   uint   `json:"userId"`
-	Username string `json:"username"`
+	UserId    uint   `json:"userId"`
+	Username  string `json:"username"`
+	SessionId string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 // NewJwtService creates a new JwtService instance
 func NewJwtService() *JwtService {
-	secretKey := []byte(g.Cfg().MustGet(context.Background(), "jwt.secretKey", "chatroom_secret_key").String())
+	ctx := context.Background()
+	secretKey := []byte(g.Cfg().MustGet(ctx, "jwt.secretKey", "chatroom_secret_key").String())
+	accessExpire := g.Cfg().MustGet(ctx, "jwt.accessExpire", consts.JwtAccessExpireTime).Int64()
+	refreshExpire := g.Cfg().MustGet(ctx, "jwt.refreshExpire", consts.JwtRefreshExpireTime).Int64()
 	return &JwtService{
-		secretKey: secretKey,
+		secretKey:     secretKey,
+		accessExpire:  time.Duration(accessExpire) * time.Second,
+		refreshExpire: time.Duration(refreshExpire) * time.Second,
+		tokenDao:      dao.NewRefreshTokenDao(),
 	}
 }
 
-// GenerateToken generates a new JWT token for a user
-func (s *JwtService) GenerateToken(user *entity.User) (string, error) {
+// GenerateToken generates a new short-lived JWT access token for a user
+func (s *JwtService) GenerateToken(user *entity.User, sessionId, jti string) (string, error) {
 	// Create claims
 	claims := JwtClaims{
-		UserId:   user.Id,
-		Username: user.Username,
+		UserId:    user.Id,
+		Username:  user.Username,
+		SessionId: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(consts.JwtExpireTime) * time.Second)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessExpire)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    consts.JwtIssuer,
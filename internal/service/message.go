@@ -6,65 +6,345 @@ import (
 	"chatroom/internal/dao"
 	"chatroom/internal/model/entity"
 	"context"
+	"time"
 
+	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/util/gconv"
 )
 
 // MessageService handles message-related business logic
 type MessageService struct {
-	messageDao *dao.MessageDao
-	roomDao    *dao.ChatRoomDao
+	messageDao    *dao.MessageDao
+	roomDao       *dao.ChatRoomDao
+	moderationDao *dao.ModerationDao
+	reactionDao   *dao.ReactionDao
+	attachmentDao *dao.AttachmentDao
+	permService   *PermissionService
+	quotaService  *QuotaService
 }
 
 // NewMessageService creates a new MessageService instance
 func NewMessageService() *MessageService {
 	return &MessageService{
-		messageDao: dao.NewMessageDao(),
-		roomDao:    dao.NewChatRoomDao(),
+		messageDao:    dao.NewMessageDao(),
+		roomDao:       dao.NewChatRoomDao(),
+		moderationDao: dao.NewModerationDao(),
+		reactionDao:   dao.NewReactionDao(),
+		attachmentDao: dao.NewAttachmentDao(),
+		permService:   NewPermissionService(),
+		quotaService:  NewQuotaService(),
 	}
 }
 
-// CreateMessage creates a new chat message
-func (s *MessageService) CreateMessage(ctx context.Context, userId uint, msg *chat.MessageReq) (uint, error) {
-	// Check if user is in the room
-	inRoom, err := s.roomDao.IsUserInRoom(ctx, msg.RoomId, userId)
-	if err != nil {
-		return 0, err
+// CreateMessage creates a new chat message and returns it with its ID and sequence number.
+// Replies are attached to the thread root of the message they reply to and msg.ParentId is
+// updated to that root. The content of image and file messages is set to the name of their
+// attachment. If the sender already sent a message with the nonce of msg within the nonce
+// window, that message is returned instead and created reports false.
+func (s *MessageService) CreateMessage(ctx context.Context, userId uint, msg *chat.MessageReq) (message *entity.Message, created bool, err error) {
+	// System messages are only generated by the server
+	if msg.Type != consts.MessageTypeText && msg.Type != consts.MessageTypeImage && msg.Type != consts.MessageTypeFile {
+		return nil, false, gerror.New("Invalid message type")
+	}
+
+	// Check if user may send messages to the room
+	if err := s.permService.Check(ctx, userId, msg.RoomId, consts.PermMessageSend); err != nil {
+		return nil, false, err
+	}
+
+	// A repeated send succeeded the first time, even if the sender was muted since
+	if msg.Nonce != "" {
+		if message, err = s.getNonceMessage(ctx, userId, msg); message != nil || err != nil {
+			return message, false, err
+		}
+	}
+
+	if err := s.checkMute(ctx, userId, msg.RoomId); err != nil {
+		return nil, false, err
 	}
-	if !inRoom {
-		return 0, gerror.New(consts.ErrNotInRoom)
+
+	// Threads are one level deep: replying to a reply joins the root's thread
+	if msg.ParentId > 0 {
+		parent, err := s.getMessage(ctx, msg.ParentId)
+		if err != nil {
+			return nil, false, err
+		}
+		if parent.RoomId != msg.RoomId {
+			return nil, false, gerror.New("Message not found")
+		}
+		if parent.ParentId > 0 {
+			msg.ParentId = parent.ParentId
+		}
+	}
+
+	// Image and file messages share an uploaded attachment instead of inline content
+	if msg.Type == consts.MessageTypeText {
+		if msg.AttachmentId > 0 {
+			return nil, false, gerror.New("Text messages cannot have attachments")
+		}
+	} else {
+		attachment, err := s.getSendableAttachment(ctx, userId, msg)
+		if err != nil {
+			return nil, false, err
+		}
+		msg.Content = attachment.Name
 	}
 
 	// Create message
-	message := &entity.Message{
-		RoomId:  msg.RoomId,
-		UserId:  userId,
-		Content: msg.Content,
-		Type:    msg.Type,
+	message = &entity.Message{
+		RoomId:       msg.RoomId,
+		UserId:       userId,
+		Content:      msg.Content,
+		Type:         msg.Type,
+		ParentId:     msg.ParentId,
+		AttachmentId: msg.AttachmentId,
+		Nonce:        msg.Nonce,
+	}
+
+	id, err := s.messageDao.Create(ctx, message)
+	if msg.Nonce != "" && dao.IsUniqueViolation(err) {
+		// The unique nonce makes a concurrent repeated send fail; return the message it stored,
+		// or store this one again if that message's nonce has expired and was released
+		existing, getErr := s.getNonceMessage(ctx, userId, msg)
+		if getErr != nil {
+			return nil, false, getErr
+		}
+		if existing != nil {
+			return existing, false, nil
+		}
+		id, err = s.messageDao.Create(ctx, message)
+		if dao.IsUniqueViolation(err) {
+			g.Log().Error(ctx, "Store message with nonce failed:", err)
+			return nil, false, gerror.New("The nonce is already in use, please try again")
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Senders have read their own message
+	if _, err := s.roomDao.MarkRead(ctx, msg.RoomId, userId, id); err != nil {
+		return nil, false, err
+	}
+
+	// The message is stored, so failing to notify mentioned members does not fail sending it
+	if err := NewNotificationService().NotifyMentions(ctx, message); err != nil {
+		g.Log().Error(ctx, "Notify mentions failed:", err)
+	}
+	return message, true, nil
+}
+
+// getNonceMessage returns the message the user sent with the nonce of msg, or nil if there is
+// none. A message sent before the nonce window has its nonce released so it can be used again.
+// The nonce may not be reused for a message to another room.
+func (s *MessageService) getNonceMessage(ctx context.Context, userId uint, msg *chat.MessageReq) (*entity.Message, error) {
+	message, err := s.messageDao.GetByNonce(ctx, userId, msg.Nonce)
+	if err != nil || message == nil {
+		return nil, err
+	}
+
+	// A window of 0 keeps nonces forever
+	window := g.Cfg().MustGet(ctx, "chat.nonceWindow", consts.DefaultMessageNonceWindow).Int()
+	if window > 0 && time.Since(message.CreatedAt) > time.Duration(window)*time.Second {
+		return nil, s.messageDao.ClearNonce(ctx, message.Id)
+	}
+
+	if message.RoomId != msg.RoomId {
+		return nil, gerror.New("The nonce was already used for a message to another chat room")
+	}
+	return message, nil
+}
+
+// GetMissedMessages retrieves up to limit messages of a room stored after the message with
+// sequence number afterSeq, in sequence order, including thread replies. The caller checks that
+// the user may read the room.
+func (s *MessageService) GetMissedMessages(ctx context.Context, userId, roomId, afterSeq uint, limit int) ([]chat.MessageRes, error) {
+	messages, err := s.messageDao.GetRoomMessagesAfterSeq(ctx, roomId, afterSeq, limit)
+	if err != nil {
+		return nil, err
 	}
+	return s.toMessageList(ctx, userId, messages)
+}
 
-	return s.messageDao.Create(ctx, message)
+// checkMute returns an error if the user is currently muted in the room
+func (s *MessageService) checkMute(ctx context.Context, userId, roomId uint) error {
+	// Muted members cannot send messages until the mute expires or is lifted
+	mute, err := s.moderationDao.GetRestriction(ctx, roomId, userId, consts.RestrictionMute)
+	if err != nil {
+		return err
+	}
+	if mute != nil && mute.IsActive() {
+		return gerror.New(consts.ErrMuted)
+	}
+	return nil
 }
 
 // GetHistory retrieves chat message history
 func (s *MessageService) GetHistory(ctx context.Context, userId uint, req *chat.HistoryReq) (*chat.HistoryRes, error) {
-	// Check if user is in the room
-	inRoom, err := s.roomDao.IsUserInRoom(ctx, req.RoomId, userId)
+	// Check if user may read the room history
+	if err := s.permService.Check(ctx, userId, req.RoomId, consts.PermMessageRead); err != nil {
+		return nil, err
+	}
+
+	// Get messages with user information, newest first
+	messages, hasOlder, hasNewer, err := s.getHistoryPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to response format
+	messageList, err := s.toMessageList(ctx, userId, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// glog.Debug(ctx, "History Response: ", messageList)
+
+	res := &chat.HistoryRes{
+		Messages: messageList,
+		Size:     req.Size,
+	}
+	if len(messageList) > 0 {
+		if hasOlder {
+			res.PrevCursor = messageList[len(messageList)-1].Id
+		}
+		if hasNewer {
+			res.NextCursor = messageList[0].Id
+		}
+	}
+	return res, nil
+}
+
+// getHistoryPage loads the messages selected by the cursor of a history request, newest first,
+// and reports whether there are older and newer messages beyond them. One extra message is
+// fetched in each direction to find out without counting the messages of the room.
+func (s *MessageService) getHistoryPage(ctx context.Context, req *chat.HistoryReq) (messages gdb.Result, hasOlder, hasNewer bool, err error) {
+	cursors := 0
+	for _, cursor := range []uint{req.Before, req.After, req.Around} {
+		if cursor == 0 {
+			continue
+		}
+		cursors++
+		if err := s.checkCursor(ctx, req.RoomId, cursor); err != nil {
+			return nil, false, false, err
+		}
+	}
+	if cursors > 1 {
+		return nil, false, false, gerror.New("Only one of before, after and around may be set")
+	}
+
+	olderSize, newerSize := req.Size, 0
+	anchorId := req.Before
+	switch {
+	case req.After > 0:
+		olderSize, newerSize = 0, req.Size
+		anchorId = req.After
+	case req.Around > 0:
+		// The anchor takes one place, newer messages get at most half of the rest
+		newerSize = (req.Size - 1) / 2
+		olderSize = req.Size - 1 - newerSize
+		anchorId = req.Around
+	}
+
+	var older, newer gdb.Result
+	if olderSize > 0 {
+		if older, err = s.messageDao.GetRoomMessagesBefore(ctx, req.RoomId, anchorId, olderSize+1); err != nil {
+			return nil, false, false, err
+		}
+		if hasOlder = len(older) > olderSize; hasOlder {
+			older = older[:olderSize]
+		}
+	}
+	if newerSize > 0 {
+		if newer, err = s.messageDao.GetRoomMessagesAfter(ctx, req.RoomId, anchorId, newerSize+1); err != nil {
+			return nil, false, false, err
+		}
+		if hasNewer = len(newer) > newerSize; hasNewer {
+			newer = newer[:newerSize]
+		}
+	}
+
+	// Newer messages are loaded oldest first
+	for i := len(newer) - 1; i >= 0; i-- {
+		messages = append(messages, newer[i])
+	}
+	switch {
+	case req.Around > 0:
+		anchor, err := s.messageDao.GetMessageWithUser(ctx, req.Around)
+		if err != nil {
+			return nil, false, false, err
+		}
+		messages = append(messages, anchor)
+	case req.After > 0:
+		// The cursor message itself is older than the page
+		hasOlder = true
+	case req.Before > 0:
+		hasNewer = true
+	}
+	return append(messages, older...), hasOlder, hasNewer, nil
+}
+
+// checkCursor checks that a history cursor is a message of the main timeline of the room
+func (s *MessageService) checkCursor(ctx context.Context, roomId, messageId uint) error {
+	message, err := s.messageDao.GetMessageById(ctx, messageId)
+	if err != nil {
+		return err
+	}
+	if message == nil || message.RoomId != roomId || message.ParentId > 0 {
+		return gerror.New("Message not found")
+	}
+	return nil
+}
+
+// GetThread retrieves a thread root with its replies; the thread of a reply is its root's thread
+func (s *MessageService) GetThread(ctx context.Context, userId uint, req *chat.ThreadReq) (*chat.ThreadRes, error) {
+	root, err := s.messageDao.GetMessageWithUser(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	if !inRoom {
-		return nil, gerror.New(consts.ErrNotInRoom)
+	if root.IsEmpty() {
+		return nil, gerror.New("Message not found")
+	}
+	if parentId := root["parent_id"].Uint(); parentId > 0 {
+		if root, err = s.messageDao.GetMessageWithUser(ctx, parentId); err != nil {
+			return nil, err
+		}
+		if root.IsEmpty() {
+			return nil, gerror.New("Message not found")
+		}
 	}
 
-	// Get messages with user information
-	messages, total, err := s.messageDao.GetRoomMessagesWithUser(ctx, req.RoomId, req.Page, req.Size)
+	if err := s.permService.Check(ctx, userId, root["room_id"].Uint(), consts.PermMessageRead); err != nil {
+		return nil, err
+	}
+
+	replies, total, err := s.messageDao.GetThreadRepliesWithUser(ctx, root["id"].Uint(), req.Page, req.Size)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to response format
+	rootList, err := s.toMessageList(ctx, userId, gdb.Result{root})
+	if err != nil {
+		return nil, err
+	}
+	replyList, err := s.toMessageList(ctx, userId, replies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat.ThreadRes{
+		Root:    rootList[0],
+		Replies: replyList,
+		Total:   total,
+		Page:    req.Page,
+		Size:    req.Size,
+	}, nil
+}
+
+// toMessageList converts messages with user information to their response format with reactions
+func (s *MessageService) toMessageList(ctx context.Context, userId uint, messages gdb.Result) ([]chat.MessageRes, error) {
 	messageList := make([]chat.MessageRes, 0, len(messages))
 	for _, m := range messages {
 		msg := chat.MessageRes{}
@@ -74,32 +354,56 @@ func (s *MessageService) GetHistory(ctx context.Context, userId uint, req *chat.
 		messageList = append(messageList, msg)
 	}
 
-	// glog.Debug(ctx, "History Response: ", messageList)
+	// Attach aggregated reactions
+	messageIds := make([]uint, 0, len(messageList))
+	for _, msg := range messageList {
+		messageIds = append(messageIds, msg.Id)
+	}
+	reactions, err := s.getReactions(ctx, userId, messageIds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messageList {
+		messageList[i].Reactions = reactions[messageList[i].Id]
+	}
 
-	return &chat.HistoryRes{
-		Messages: messageList,
-		Total:    total,
-		Page:     req.Page,
-		Size:     req.Size,
-	}, nil
+	// Attach attachment metadata of image and file messages
+	attachmentIds := make([]uint, 0)
+	for _, m := range messages {
+		if id := m["attachment_id"].Uint(); id > 0 {
+			attachmentIds = append(attachmentIds, id)
+		}
+	}
+	attachments, err := s.getAttachments(ctx, attachmentIds)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range messages {
+		messageList[i].Attachment = attachments[m["attachment_id"].Uint()]
+	}
+	return messageList, nil
 }
 
 // GetRoomMembers retrieves all members in a chat room
 func (s *MessageService) GetRoomMembers(ctx context.Context, userId uint, req *chat.RoomMembersReq) (*chat.RoomMembersRes, error) {
-	// Check if user is in the room
-	inRoom, err := s.roomDao.IsUserInRoom(ctx, req.Id, userId)
-	if err != nil {
+	// Check if user may list the room members
+	if err := s.permService.Check(ctx, userId, req.Id, consts.PermMemberList); err != nil {
 		return nil, err
 	}
-	if !inRoom {
-		return nil, gerror.New(consts.ErrNotInRoom)
-	}
 
-	// Get room members
+	// Get room members and their room roles
 	users, err := s.roomDao.ListRoomUsers(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
+	memberships, err := s.roomDao.ListMembers(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[uint]string, len(memberships))
+	for _, m := range memberships {
+		roles[m.UserId] = m.Role
+	}
 
 	// Convert to response format
 	members := make([]chat.Member, 0, len(users))
@@ -110,6 +414,7 @@ func (s *MessageService) GetRoomMembers(ctx context.Context, userId uint, req *c
 			Nickname: u.Nickname,
 			Avatar:   u.Avatar,
 			Status:   u.Status,
+			Role:     roles[u.Id],
 		})
 	}
 
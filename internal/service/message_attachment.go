@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/model/entity"
+	"chatroom/internal/storage"
+	"chatroom/utility/imaging"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/grand"
+)
+
+// maxAttachmentNameLength is the maximum length of stored attachment names in characters
+const maxAttachmentNameLength = 255
+
+// defaultThumbnailSizes are the bounding box sizes of image thumbnails in pixels
+var defaultThumbnailSizes = []int{160, 480, 960}
+
+// UploadAttachment stores a file uploaded to a room and returns its metadata.
+// The file is shared by sending an image or file message referencing the attachment.
+func (s *MessageService) UploadAttachment(ctx context.Context, userId, roomId uint, file *ghttp.UploadFile) (*chat.Attachment, error) {
+	if err := s.checkUpload(ctx, userId, roomId); err != nil {
+		return nil, err
+	}
+
+	maxSize := g.Cfg().MustGet(ctx, "chat.attachmentMaxSize", consts.DefaultAttachmentMaxSize).Int64()
+	if file.Size <= 0 {
+		return nil, gerror.New("File is empty")
+	}
+	if maxSize > 0 && file.Size > maxSize {
+		return nil, gerror.Newf("File is too large, the limit is %d bytes", maxSize)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var attachment *entity.Attachment
+	var thumbnails []entity.AttachmentThumbnail
+	err = s.quotaService.Reserve(ctx, userId, roomId, file.Size, func() (err error) {
+		attachment, thumbnails, err = s.storeAttachment(ctx, userId, roomId, file.Filename, file.Size, src)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAttachment(attachment, thumbnails), nil
+}
+
+// checkUpload checks that a user may upload files to a room. Uploading is the first step of
+// sending, so the same checks apply.
+func (s *MessageService) checkUpload(ctx context.Context, userId, roomId uint) error {
+	if err := s.permService.Check(ctx, userId, roomId, consts.PermMessageSend); err != nil {
+		return err
+	}
+	return s.checkMute(ctx, userId, roomId)
+}
+
+// storeAttachment stores size bytes of file contents in the storage backend and records them
+// as an attachment of a room, together with the thumbnails of images
+func (s *MessageService) storeAttachment(ctx context.Context, userId, roomId uint, filename string, size int64, src io.Reader) (*entity.Attachment, []entity.AttachmentThumbnail, error) {
+	store, err := storage.Default(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The MIME type is detected from the contents instead of trusting the client
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+
+	attachment := &entity.Attachment{
+		UserId:     userId,
+		RoomId:     roomId,
+		StorageKey: path.Join(time.Now().Format("2006/01/02"), grand.S(32)),
+		Name:       attachmentName(filename),
+		Size:       size,
+		MimeType:   http.DetectContentType(head),
+	}
+	content := io.MultiReader(bytes.NewReader(head), src)
+	var images []imaging.Thumbnail
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		if content, images, err = prepareImage(ctx, attachment, content); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	hash := sha256.New()
+	content = io.TeeReader(content, hash)
+	if err := store.Put(ctx, attachment.StorageKey, content, attachment.Size, attachment.MimeType); err != nil {
+		return nil, nil, err
+	}
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	storageKeys := []string{attachment.StorageKey}
+	thumbnails := make([]entity.AttachmentThumbnail, 0, len(images))
+	for _, image := range images {
+		thumbnail := entity.AttachmentThumbnail{
+			Size:       image.Size,
+			Width:      image.Width,
+			Height:     image.Height,
+			StorageKey: fmt.Sprintf("%s_%d", attachment.StorageKey, image.Size),
+			MimeType:   image.MimeType,
+		}
+		err := store.Put(ctx, thumbnail.StorageKey, bytes.NewReader(image.Data), int64(len(image.Data)), image.MimeType)
+		if err != nil {
+			deleteStoredObjects(ctx, storageKeys...)
+			return nil, nil, err
+		}
+		storageKeys = append(storageKeys, thumbnail.StorageKey)
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	if attachment.Id, err = s.attachmentDao.Create(ctx, attachment, thumbnails); err != nil {
+		deleteStoredObjects(ctx, storageKeys...)
+		return nil, nil, err
+	}
+	return attachment, thumbnails, nil
+}
+
+// prepareImage decodes an uploaded image to validate it and re-encodes it without EXIF and other
+// metadata, returning the cleaned contents and thumbnails. Files that only look like images, and
+// images too large to decode, are stored as plain files so they are never displayed inline.
+// Images are read into memory, so only files within the attachment size limit are processed;
+// larger ones, e.g. resumable uploads, are streamed to storage unchanged.
+func prepareImage(ctx context.Context, attachment *entity.Attachment, content io.Reader) (io.Reader, []imaging.Thumbnail, error) {
+	maxSize := int64(consts.MaxImageSize)
+	if limit := g.Cfg().MustGet(ctx, "chat.attachmentMaxSize", consts.DefaultAttachmentMaxSize).Int64(); limit > 0 {
+		maxSize = min(maxSize, limit)
+	}
+	if attachment.Size > maxSize {
+		attachment.MimeType = "application/octet-stream"
+		return content, nil, nil
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sizes := g.Cfg().MustGet(ctx, "chat.thumbnailSizes", defaultThumbnailSizes).Ints()
+	image, err := imaging.Process(data, sizes, consts.MaxImagePixels)
+	if err == imaging.ErrUnsupported || err == imaging.ErrTooLarge {
+		attachment.MimeType = "application/octet-stream"
+		return bytes.NewReader(data), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachment.Size = int64(len(image.Data))
+	attachment.MimeType = image.MimeType
+	attachment.Width, attachment.Height = image.Width, image.Height
+	return bytes.NewReader(image.Data), image.Thumbnails, nil
+}
+
+// OpenAttachment checks that a user may read the room of an attachment and opens its contents.
+// The caller must close the returned reader.
+func (s *MessageService) OpenAttachment(ctx context.Context, userId, id uint) (*entity.Attachment, io.ReadCloser, error) {
+	attachment, err := s.getReadableAttachment(ctx, userId, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := openStoredObject(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// OpenThumbnail checks that a user may read the room of an image attachment and opens the
+// contents of its thumbnail for a bounding box size. The caller must close the returned reader.
+func (s *MessageService) OpenThumbnail(ctx context.Context, userId, id uint, size int) (*entity.AttachmentThumbnail, io.ReadCloser, error) {
+	if _, err := s.getReadableAttachment(ctx, userId, id); err != nil {
+		return nil, nil, err
+	}
+	thumbnail, err := s.attachmentDao.GetThumbnail(ctx, id, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	if thumbnail == nil {
+		return nil, nil, gerror.New("Thumbnail not found")
+	}
+	content, err := openStoredObject(ctx, thumbnail.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return thumbnail, content, nil
+}
+
+// getReadableAttachment returns an attachment after checking that a user may read its room
+func (s *MessageService) getReadableAttachment(ctx context.Context, userId, id uint) (*entity.Attachment, error) {
+	attachment, err := s.attachmentDao.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil {
+		return nil, gerror.New("Attachment not found")
+	}
+	if err := s.permService.Check(ctx, userId, attachment.RoomId, consts.PermMessageRead); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// getSendableAttachment returns the attachment of an image or file message after checking that the
+// sender uploaded it to the message's room and has not sent it before
+func (s *MessageService) getSendableAttachment(ctx context.Context, userId uint, msg *chat.MessageReq) (*entity.Attachment, error) {
+	if msg.AttachmentId == 0 {
+		return nil, gerror.New("Attachment is required")
+	}
+	attachment, err := s.attachmentDao.GetByID(ctx, msg.AttachmentId)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil || attachment.UserId != userId || attachment.RoomId != msg.RoomId {
+		return nil, gerror.New("Attachment not found")
+	}
+	if msg.Type == consts.MessageTypeImage && !strings.HasPrefix(attachment.MimeType, "image/") {
+		return nil, gerror.New("Attachment is not an image")
+	}
+
+	sent, err := s.messageDao.IsAttachmentSent(ctx, attachment.Id)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		return nil, gerror.New("Attachment has already been sent")
+	}
+	return attachment, nil
+}
+
+// getAttachments returns the response format of the attachments with the given IDs
+func (s *MessageService) getAttachments(ctx context.Context, ids []uint) (map[uint]*chat.Attachment, error) {
+	attachments, err := s.attachmentDao.ListByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	thumbnails, err := s.attachmentDao.ListThumbnails(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	thumbnailsByAttachment := make(map[uint][]entity.AttachmentThumbnail)
+	for _, thumbnail := range thumbnails {
+		thumbnailsByAttachment[thumbnail.AttachmentId] = append(thumbnailsByAttachment[thumbnail.AttachmentId], thumbnail)
+	}
+
+	result := make(map[uint]*chat.Attachment, len(attachments))
+	for i := range attachments {
+		result[attachments[i].Id] = toAttachment(&attachments[i], thumbnailsByAttachment[attachments[i].Id])
+	}
+	return result, nil
+}
+
+// toAttachment converts an attachment entity and its thumbnails to the response format
+func toAttachment(attachment *entity.Attachment, thumbnails []entity.AttachmentThumbnail) *chat.Attachment {
+	url := fmt.Sprintf("/api/chat/attachment/%d", attachment.Id)
+	result := &chat.Attachment{
+		Id:       attachment.Id,
+		Name:     attachment.Name,
+		Size:     attachment.Size,
+		MimeType: attachment.MimeType,
+		Checksum: attachment.Checksum,
+		Url:      url,
+		Width:    attachment.Width,
+		Height:   attachment.Height,
+	}
+	for _, thumbnail := range thumbnails {
+		result.Thumbnails = append(result.Thumbnails, chat.Thumbnail{
+			Size:   thumbnail.Size,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+			Url:    fmt.Sprintf("%s/thumbnail/%d", url, thumbnail.Size),
+		})
+	}
+	return result
+}
+
+// attachmentName strips the directories some browsers include in upload file names
+func attachmentName(filename string) string {
+	name := strings.TrimSpace(filepath.Base(strings.ReplaceAll(filename, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return gstr.SubStrRune(name, 0, maxAttachmentNameLength)
+}
+
+// openStoredObject opens attachment contents in the storage backend
+func openStoredObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	store, err := storage.Default(ctx)
+	if err != nil {
+		return nil, err
+	}
+	content, err := store.Get(ctx, key)
+	if err == storage.ErrNotFound {
+		return nil, gerror.New("Attachment not found")
+	}
+	return content, err
+}
+
+// deleteAttachment deletes an attachment that no message references, together with its contents
+func (s *MessageService) deleteAttachment(ctx context.Context, id uint) error {
+	storageKeys, err := s.attachmentDao.ListStorageKeys(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.attachmentDao.Delete(ctx, id); err != nil {
+		return err
+	}
+	deleteStoredObjects(ctx, storageKeys...)
+	return nil
+}
+
+// deleteStoredObjects deletes attachment contents from the storage backend. Failures are only
+// logged, as the metadata referencing the contents is already gone.
+func deleteStoredObjects(ctx context.Context, keys ...string) {
+	store, err := storage.Default(ctx)
+	if err != nil {
+		g.Log().Error(ctx, "Open attachment storage failed:", err)
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			g.Log().Error(ctx, "Delete attachment contents failed:", key, err)
+		}
+	}
+}
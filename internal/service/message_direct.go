@@ -0,0 +1,147 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// SendDirect sends a direct message to a user, creating their conversation on the first message.
+// A repeated send with the same nonce returns the original message without delivering it again.
+func (s *MessageService) SendDirect(ctx context.Context, userId uint, req *chat.DirectSendReq) (*chat.DirectSendRes, error) {
+	if req.UserId == userId {
+		return nil, gerror.New("You cannot send direct messages to yourself")
+	}
+
+	userDao := dao.NewUserDao()
+	sender, err := userDao.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := userDao.GetByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if sender == nil || peer == nil {
+		return nil, gerror.New("User not found")
+	}
+
+	roomId, err := s.openDirectRoom(ctx, userId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &chat.MessageReq{
+		Type:         req.Type,
+		Content:      req.Content,
+		RoomId:       roomId,
+		AttachmentId: req.AttachmentId,
+		Nonce:        req.Nonce,
+	}
+	message, created, err := s.CreateMessage(ctx, userId, msg)
+	if err != nil {
+		return nil, err
+	}
+	res := &chat.DirectSendRes{RoomId: roomId, MessageId: message.Id}
+	if !created {
+		return res, nil
+	}
+	if err := s.deliverMessage(ctx, sender, true, msg, message); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListDirect returns the direct conversations of a user
+func (s *MessageService) ListDirect(ctx context.Context, userId uint) (*chat.DirectListRes, error) {
+	rooms, err := s.roomDao.ListDirectRooms(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	userDao := dao.NewUserDao()
+	list := make([]chat.DirectConversation, 0, len(rooms))
+	for _, room := range rooms {
+		members, err := s.roomDao.ListRoomUsers(ctx, room.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		var peer *entity.User
+		for i := range members {
+			if members[i].Id != userId {
+				peer = &members[i]
+			}
+		}
+		if peer == nil {
+			continue
+		}
+
+		conversation := chat.DirectConversation{
+			RoomId: room.Id,
+			Peer: chat.Member{
+				Id:       peer.Id,
+				Username: peer.Username,
+				Nickname: peer.Nickname,
+				Avatar:   peer.Avatar,
+				Status:   peer.Status,
+			},
+		}
+
+		last, err := s.messageDao.GetLastRoomMessage(ctx, room.Id)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			sender := peer
+			if last.UserId == userId {
+				if sender, err = userDao.GetByID(ctx, userId); err != nil {
+					return nil, err
+				}
+			}
+			conversation.LastMessage = &chat.MessageRes{
+				Id:        last.Id,
+				Type:      last.Type,
+				Content:   last.Content,
+				RoomId:    last.RoomId,
+				UserId:    last.UserId,
+				Username:  sender.Username,
+				Nickname:  sender.Nickname,
+				Avatar:    sender.Avatar,
+				Timestamp: last.CreatedAt.Format("2006-01-02 15:04:05"),
+				EditedAt:  formatOptionalTime(last.EditedAt),
+				Deleted:   last.IsDeleted(),
+			}
+		}
+
+		list = append(list, conversation)
+	}
+
+	return &chat.DirectListRes{List: list}, nil
+}
+
+// openDirectRoom returns the direct conversation between two users, creating it if needed
+func (s *MessageService) openDirectRoom(ctx context.Context, userId, peerId uint) (uint, error) {
+	room, err := s.roomDao.GetDirectRoom(ctx, userId, peerId)
+	if err != nil {
+		return 0, err
+	}
+	if room != nil {
+		return room.Id, nil
+	}
+
+	roomId, err := s.roomDao.CreateDirectRoom(ctx, userId, peerId)
+	if err == nil {
+		return roomId, nil
+	}
+
+	// The unique dm_key makes a concurrent first message fail; use the room it created
+	room, getErr := s.roomDao.GetDirectRoom(ctx, userId, peerId)
+	if getErr != nil || room == nil {
+		return 0, err
+	}
+	return room.Id, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// EditMessage replaces the content of a text message within the configured edit window
+func (s *MessageService) EditMessage(ctx context.Context, userId uint, req *chat.EditMessageReq) (*chat.EditMessageRes, error) {
+	message, err := s.getMessage(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if message.UserId != userId {
+		return nil, gerror.New("You can only edit your own messages")
+	}
+	if message.Type != consts.MessageTypeText {
+		return nil, gerror.New("Only text messages can be edited")
+	}
+
+	// A window of 0 allows editing at any time
+	window := g.Cfg().MustGet(ctx, "chat.editWindow", consts.DefaultMessageEditWindow).Int()
+	if window > 0 && time.Since(message.CreatedAt) > time.Duration(window)*time.Second {
+		return nil, gerror.New("The edit window for this message has expired")
+	}
+
+	// Editing is sending, so it requires the same permissions
+	if err := s.permService.Check(ctx, userId, message.RoomId, consts.PermMessageSend); err != nil {
+		return nil, err
+	}
+	if err := s.checkMute(ctx, userId, message.RoomId); err != nil {
+		return nil, err
+	}
+
+	if req.Content == message.Content {
+		return &chat.EditMessageRes{Success: true}, nil
+	}
+	if err := s.messageDao.UpdateContent(ctx, message, req.Content); err != nil {
+		return nil, err
+	}
+
+	user, err := dao.NewUserDao().GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open clients replace the message content in place
+	now := time.Now()
+	GetWebSocketManager().broadcastToRoom(message.RoomId, WebSocketMessage{
+		Event:   consts.WsMsgTypeEdit,
+		Id:      message.Id,
+		Content: req.Content,
+		Data: g.Map{
+			"roomId":   message.RoomId,
+			"editedAt": now.Format("2006-01-02 15:04:05"),
+		},
+		Timestamp: now.Format(time.RFC3339),
+		UserId:    user.Id,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+	})
+
+	return &chat.EditMessageRes{Success: true}, nil
+}
+
+// DeleteMessage turns a message into a tombstone. Authors may delete their messages at any
+// time; deleting other members' messages requires the message delete permission.
+func (s *MessageService) DeleteMessage(ctx context.Context, userId uint, req *chat.DeleteMessageReq) (*chat.DeleteMessageRes, error) {
+	message, err := s.getMessage(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if message.UserId != userId {
+		if err := s.permService.Check(ctx, userId, message.RoomId, consts.PermMessageDelete); err != nil {
+			return nil, err
+		}
+	}
+
+	storageKeys, err := s.attachmentDao.ListStorageKeys(ctx, message.AttachmentId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.messageDao.SoftDelete(ctx, message.Id, userId); err != nil {
+		return nil, err
+	}
+	deleteStoredObjects(ctx, storageKeys...)
+
+	// Open clients replace the message with a tombstone
+	GetWebSocketManager().broadcastToRoom(message.RoomId, WebSocketMessage{
+		Event: consts.WsMsgTypeDelete,
+		Id:    message.Id,
+		Data: g.Map{
+			"roomId":    message.RoomId,
+			"deletedBy": userId,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserId:    message.UserId,
+	})
+
+	return &chat.DeleteMessageRes{Success: true}, nil
+}
+
+// GetMessageEdits returns the previous contents of an edited message
+func (s *MessageService) GetMessageEdits(ctx context.Context, userId uint, req *chat.MessageEditsReq) (*chat.MessageEditsRes, error) {
+	message, err := s.getMessage(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permService.Check(ctx, userId, message.RoomId, consts.PermMessageRead); err != nil {
+		return nil, err
+	}
+
+	edits, err := s.messageDao.ListEdits(ctx, message.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]chat.MessageEdit, 0, len(edits))
+	for _, e := range edits {
+		list = append(list, chat.MessageEdit{
+			Content:  e.Content,
+			EditedAt: e.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return &chat.MessageEditsRes{List: list}, nil
+}
+
+// getMessage retrieves a message that has not been deleted
+func (s *MessageService) getMessage(ctx context.Context, id uint) (*entity.Message, error) {
+	message, err := s.messageDao.GetMessageById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.IsDeleted() {
+		return nil, gerror.New("Message not found")
+	}
+	return message, nil
+}
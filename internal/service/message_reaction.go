@@ -0,0 +1,147 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// maxEmojiLength is the maximum length of a reaction emoji in characters
+const maxEmojiLength = 32
+
+// AddReaction adds a user's emoji reaction to a message and returns the message's reactions
+func (s *MessageService) AddReaction(ctx context.Context, userId, messageId uint, emoji string) ([]chat.Reaction, error) {
+	emoji, err := normalizeEmoji(emoji)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.getMessage(ctx, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reacting is a lightweight form of sending, so muted members cannot react
+	if err := s.permService.Check(ctx, userId, message.RoomId, consts.PermMessageSend); err != nil {
+		return nil, err
+	}
+	if err := s.checkMute(ctx, userId, message.RoomId); err != nil {
+		return nil, err
+	}
+
+	added, err := s.reactionDao.Add(ctx, message.Id, userId, emoji)
+	if err != nil {
+		return nil, err
+	}
+	return s.reactionChanged(ctx, userId, message, emoji, added, true)
+}
+
+// RemoveReaction removes a user's emoji reaction from a message and returns the message's reactions
+func (s *MessageService) RemoveReaction(ctx context.Context, userId, messageId uint, emoji string) ([]chat.Reaction, error) {
+	emoji, err := normalizeEmoji(emoji)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.getMessage(ctx, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permService.Check(ctx, userId, message.RoomId, consts.PermMessageRead); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.reactionDao.Remove(ctx, message.Id, userId, emoji)
+	if err != nil {
+		return nil, err
+	}
+	return s.reactionChanged(ctx, userId, message, emoji, removed, false)
+}
+
+// reactionChanged broadcasts a changed reaction to the room and returns the message's reactions
+func (s *MessageService) reactionChanged(ctx context.Context, userId uint, message *entity.Message, emoji string, changed, added bool) ([]chat.Reaction, error) {
+	reactions, err := s.getReactions(ctx, userId, []uint{message.Id})
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return reactions[message.Id], nil
+	}
+
+	user, err := dao.NewUserDao().GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, r := range reactions[message.Id] {
+		if r.Emoji == emoji {
+			count = r.Count
+		}
+	}
+
+	// Open clients update the reaction counts in place
+	GetWebSocketManager().broadcastToRoom(message.RoomId, WebSocketMessage{
+		Event:   consts.WsMsgTypeReaction,
+		Id:      message.Id,
+		Content: emoji,
+		Data: g.Map{
+			"roomId": message.RoomId,
+			"count":  count,
+			"added":  added,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserId:    user.Id,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+	})
+
+	return reactions[message.Id], nil
+}
+
+// getReactions aggregates the reactions of messages, flagging the ones of the given user
+func (s *MessageService) getReactions(ctx context.Context, userId uint, messageIds []uint) (map[uint][]chat.Reaction, error) {
+	counts, err := s.reactionDao.CountByMessages(ctx, messageIds)
+	if err != nil {
+		return nil, err
+	}
+	userEmojis, err := s.reactionDao.ListUserEmojis(ctx, messageIds, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make(map[uint][]chat.Reaction, len(messageIds))
+	for _, id := range messageIds {
+		reactions[id] = []chat.Reaction{}
+	}
+	for _, c := range counts {
+		reacted := false
+		for _, emoji := range userEmojis[c.MessageId] {
+			if emoji == c.Emoji {
+				reacted = true
+			}
+		}
+		reactions[c.MessageId] = append(reactions[c.MessageId], chat.Reaction{
+			Emoji:   c.Emoji,
+			Count:   c.Count,
+			Reacted: reacted,
+		})
+	}
+	return reactions, nil
+}
+
+// normalizeEmoji trims a reaction emoji and checks its length
+func normalizeEmoji(emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiLength {
+		return "", gerror.New("Invalid reaction emoji")
+	}
+	return emoji, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// GetUnread returns the unread message counts of all rooms a user is in
+func (s *MessageService) GetUnread(ctx context.Context, userId uint) (*chat.UnreadRes, error) {
+	rooms, err := s.roomDao.GetUserRooms(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.messageDao.ListUnread(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &chat.UnreadRes{List: make([]chat.RoomUnread, 0, len(rooms))}
+	for _, room := range rooms {
+		// Rooms without unread messages are listed with a count of 0
+		count := unread[room.Id]
+		res.List = append(res.List, chat.RoomUnread{
+			RoomId:            room.Id,
+			Name:              room.Name,
+			Type:              room.Type,
+			Unread:            count.Unread,
+			LastReadMessageId: count.LastReadMessageId,
+		})
+		res.Total += count.Unread
+	}
+	return res, nil
+}
+
+// MarkRead marks the messages of a room read up to a message and broadcasts a read receipt
+func (s *MessageService) MarkRead(ctx context.Context, userId, roomId, messageId uint) error {
+	if err := s.permService.Check(ctx, userId, roomId, consts.PermMessageRead); err != nil {
+		return err
+	}
+
+	message, err := s.messageDao.GetMessageById(ctx, messageId)
+	if err != nil {
+		return err
+	}
+	if message == nil || message.RoomId != roomId {
+		return gerror.New("Message not found")
+	}
+
+	changed, err := s.roomDao.MarkRead(ctx, roomId, userId, messageId)
+	if err != nil || !changed {
+		return err
+	}
+
+	// Read receipts are only useful and affordable in small rooms
+	maxMembers := g.Cfg().MustGet(ctx, "chat.readReceiptMaxMembers", consts.DefaultReadReceiptMaxMembers).Int()
+	userCount, err := s.roomDao.GetUserCount(ctx, roomId)
+	if err != nil {
+		return err
+	}
+	if userCount > maxMembers {
+		return nil
+	}
+
+	user, err := dao.NewUserDao().GetByID(ctx, userId)
+	if err != nil || user == nil {
+		return err
+	}
+	GetWebSocketManager().broadcastToRoom(roomId, WebSocketMessage{
+		Event:     consts.WsMsgTypeReadReceipt,
+		Id:        messageId,
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserId:    user.Id,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+	})
+	return nil
+}
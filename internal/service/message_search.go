@@ -0,0 +1,78 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// Search searches the text messages of the rooms the user belongs to
+func (s *MessageService) Search(ctx context.Context, userId uint, req *chat.SearchReq) (*chat.SearchRes, error) {
+	filter := &dao.MessageSearchFilter{
+		Query:  req.Query,
+		UserId: req.UserId,
+		From:   req.From,
+	}
+
+	// Only rooms the user is a member of are searched
+	rooms, err := s.roomDao.GetUserRooms(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, room := range rooms {
+		if req.RoomId == 0 || room.Id == req.RoomId {
+			filter.RoomIds = append(filter.RoomIds, room.Id)
+		}
+	}
+	if req.RoomId > 0 && len(filter.RoomIds) == 0 {
+		return nil, gerror.New(consts.ErrNotInRoom)
+	}
+
+	// The end date is inclusive
+	if req.To != "" {
+		to, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return nil, err
+		}
+		filter.Until = to.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+
+	results, total, err := s.messageDao.Search(ctx, filter, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.toMessageList(ctx, userId, results)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]chat.SearchResult, 0, len(messages))
+	for i, message := range messages {
+		list = append(list, chat.SearchResult{
+			Message:  message,
+			RoomName: results[i]["room_name"].String(),
+			Snippet:  highlightSnippet(results[i]["snippet"].String()),
+		})
+	}
+
+	return &chat.SearchRes{
+		List:  list,
+		Total: total,
+		Page:  req.Page,
+		Size:  req.Size,
+	}, nil
+}
+
+// highlightSnippet HTML-escapes a search snippet and wraps its matches in <mark> tags
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(
+		dao.SearchMatchStart, "<mark>",
+		dao.SearchMatchEnd, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
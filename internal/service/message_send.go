@@ -0,0 +1,92 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// SendMessage sends a message to a room without a WebSocket connection, e.g. from bots and
+// scripts. It is stored and delivered to the live connections of the room like a message sent
+// over WebSocket. A repeated send with the same nonce returns the original message without
+// delivering it again.
+func (s *MessageService) SendMessage(ctx context.Context, userId uint, req *chat.SendMessageReq) (*chat.SendMessageRes, error) {
+	sender, err := dao.NewUserDao().GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if sender == nil {
+		return nil, gerror.New("User not found")
+	}
+
+	msg := &chat.MessageReq{
+		Type:         req.Type,
+		Content:      req.Content,
+		RoomId:       req.RoomId,
+		ParentId:     req.ParentId,
+		AttachmentId: req.AttachmentId,
+		Nonce:        req.Nonce,
+	}
+	message, created, err := s.CreateMessage(ctx, userId, msg)
+	if err != nil {
+		return nil, err
+	}
+	res := &chat.SendMessageRes{MessageId: message.Id, Seq: message.Seq}
+	if !created {
+		return res, nil
+	}
+
+	room, err := s.roomDao.GetByID(ctx, req.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, gerror.New("Chat room not found")
+	}
+	if err := s.deliverMessage(ctx, sender, room.Type == consts.RoomTypeDirect, msg, message); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// deliverMessage delivers a stored message to the live connections of its room. Thread replies
+// only reach the thread participants and direct messages also reach the peer's connections to
+// other rooms.
+func (s *MessageService) deliverMessage(ctx context.Context, sender *entity.User, direct bool, msg *chat.MessageReq, message *entity.Message) error {
+	wsMsg := WebSocketMessage{
+		Event:     consts.WsMsgTypeMessage,
+		Type:      msg.Type,
+		Id:        message.Id,
+		Seq:       message.Seq,
+		ParentId:  msg.ParentId,
+		Content:   msg.Content,
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserId:    sender.Id,
+		Username:  sender.Username,
+		Nickname:  sender.Nickname,
+		Avatar:    sender.Avatar,
+	}
+	if msg.AttachmentId > 0 {
+		attachments, err := s.getAttachments(ctx, []uint{msg.AttachmentId})
+		if err != nil {
+			return err
+		}
+		wsMsg.Attachment = attachments[msg.AttachmentId]
+	}
+
+	manager := GetWebSocketManager()
+	switch {
+	case wsMsg.ParentId > 0:
+		manager.deliverReply(ctx, msg.RoomId, wsMsg)
+	case direct:
+		manager.deliverDirect(ctx, msg.RoomId, wsMsg)
+	default:
+		manager.broadcastToRoom(msg.RoomId, wsMsg)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// mentionPattern matches @username, @here and @all not preceded by a word character, so e-mail addresses are ignored
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_])@([a-zA-Z0-9_]+)`)
+
+// notificationExcerptLength is the maximum length of the message excerpt stored with a notification
+const notificationExcerptLength = 100
+
+// NotificationService manages the notification inbox of users
+type NotificationService struct {
+	notificationDao *dao.NotificationDao
+	roomDao         *dao.ChatRoomDao
+	userDao         *dao.UserDao
+}
+
+// NewNotificationService creates a new NotificationService instance
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		notificationDao: dao.NewNotificationDao(),
+		roomDao:         dao.NewChatRoomDao(),
+		userDao:         dao.NewUserDao(),
+	}
+}
+
+// List returns the notifications of a user
+func (s *NotificationService) List(ctx context.Context, userId uint, req *chat.NotificationListReq) (*chat.NotificationListRes, error) {
+	notifications, total, err := s.notificationDao.List(ctx, userId, req.Unread, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationDao.CountUnread(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]chat.Notification, 0, len(notifications))
+	for i := range notifications {
+		notification, err := s.toNotification(ctx, &notifications[i])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, notification)
+	}
+
+	return &chat.NotificationListRes{
+		List:   list,
+		Total:  total,
+		Unread: unread,
+		Page:   req.Page,
+		Size:   req.Size,
+	}, nil
+}
+
+// Dismiss marks a notification of a user read
+func (s *NotificationService) Dismiss(ctx context.Context, userId, id uint) error {
+	found, err := s.notificationDao.MarkRead(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return gerror.New("Notification not found")
+	}
+	return nil
+}
+
+// DismissAll marks all notifications of a user read
+func (s *NotificationService) DismissAll(ctx context.Context, userId uint) error {
+	return s.notificationDao.MarkAllRead(ctx, userId)
+}
+
+// NotifyMentions creates notifications for the room members mentioned in a text message with
+// @username, @here or @all and pushes them to every live connection of those members.
+// The sender is never notified of their own message.
+func (s *NotificationService) NotifyMentions(ctx context.Context, message *entity.Message) error {
+	if message.Type != consts.MessageTypeText {
+		return nil
+	}
+	recipients, err := s.mentionedMembers(ctx, message)
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	actor, err := s.userDao.GetByID(ctx, message.UserId)
+	if err != nil {
+		return err
+	}
+	if actor == nil {
+		return gerror.New("User not found")
+	}
+
+	for _, member := range recipients {
+		notification := &entity.Notification{
+			UserId:    member.userId,
+			Type:      member.notificationType,
+			RoomId:    message.RoomId,
+			MessageId: message.Id,
+			ActorId:   actor.Id,
+			Content:   gstr.SubStrRune(message.Content, 0, notificationExcerptLength),
+			CreatedAt: time.Now(),
+		}
+		if notification.Id, err = s.notificationDao.Create(ctx, notification); err != nil {
+			return err
+		}
+
+		data := chat.Notification{
+			Id:            notification.Id,
+			Type:          notification.Type,
+			RoomId:        notification.RoomId,
+			MessageId:     notification.MessageId,
+			ActorId:       actor.Id,
+			ActorNickname: actor.Nickname,
+			Content:       notification.Content,
+			CreatedAt:     notification.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		msgBytes, _ := json.Marshal(WebSocketMessage{
+			Event:     consts.WsMsgTypeNotification,
+			RoomId:    message.RoomId,
+			Id:        message.Id,
+			Content:   fmt.Sprintf("%s 提到了你：%s", actor.Nickname, data.Content),
+			Timestamp: time.Now().Format(time.RFC3339),
+			UserId:    actor.Id,
+			Username:  actor.Username,
+			Nickname:  actor.Nickname,
+			Avatar:    actor.Avatar,
+			Data:      data,
+		})
+		GetWebSocketManager().sendToUser(member.userId, msgBytes)
+	}
+	return nil
+}
+
+// mentionedMember is a room member to notify and the kind of mention that reached them
+type mentionedMember struct {
+	userId           uint
+	notificationType string
+}
+
+// mentionedMembers resolves the mentions of a message to the room members to notify, in order
+// of mention. Explicit @username mentions take precedence over @here and @all.
+func (s *NotificationService) mentionedMembers(ctx context.Context, message *entity.Message) ([]mentionedMember, error) {
+	var (
+		usernames []string
+		here, all bool
+		seen      = make(map[string]bool)
+	)
+	for _, match := range mentionPattern.FindAllStringSubmatch(message.Content, -1) {
+		name := match[1]
+		switch {
+		case name == "here":
+			here = true
+		case name == "all":
+			all = true
+		case !seen[name] && len(usernames) < consts.MaxMentionsPerMessage:
+			seen[name] = true
+			usernames = append(usernames, name)
+		}
+	}
+
+	var recipients []mentionedMember
+	notified := map[uint]bool{message.UserId: true}
+	for _, username := range usernames {
+		user, err := s.userDao.GetByUsername(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil || notified[user.Id] {
+			continue
+		}
+		isInRoom, err := s.roomDao.IsUserInRoom(ctx, message.RoomId, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		if isInRoom {
+			notified[user.Id] = true
+			recipients = append(recipients, mentionedMember{user.Id, consts.NotificationTypeMention})
+		}
+	}
+	if !here && !all {
+		return recipients, nil
+	}
+
+	members, err := s.roomDao.ListMembers(ctx, message.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	manager := GetWebSocketManager()
+	for _, member := range members {
+		switch {
+		case notified[member.UserId]:
+		case all:
+			recipients = append(recipients, mentionedMember{member.UserId, consts.NotificationTypeAll})
+		case manager.isOnline(member.UserId):
+			recipients = append(recipients, mentionedMember{member.UserId, consts.NotificationTypeHere})
+		}
+	}
+	return recipients, nil
+}
+
+// toNotification converts a notification entity to its response format
+func (s *NotificationService) toNotification(ctx context.Context, notification *entity.Notification) (chat.Notification, error) {
+	res := chat.Notification{
+		Id:        notification.Id,
+		Type:      notification.Type,
+		RoomId:    notification.RoomId,
+		MessageId: notification.MessageId,
+		ActorId:   notification.ActorId,
+		Content:   notification.Content,
+		IsRead:    notification.IsRead,
+		CreatedAt: notification.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	actor, err := s.userDao.GetByID(ctx, notification.ActorId)
+	if err != nil {
+		return res, err
+	}
+	if actor != nil {
+		res.ActorNickname = actor.Nickname
+	}
+	return res, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// PermissionService resolves user roles and checks their permissions
+type PermissionService struct {
+	userDao *dao.UserDao
+	roomDao *dao.ChatRoomDao
+	roleDao *dao.RoleDao
+}
+
+// NewPermissionService creates a new PermissionService instance
+func NewPermissionService() *PermissionService {
+	return &PermissionService{
+		userDao: dao.NewUserDao(),
+		roomDao: dao.NewChatRoomDao(),
+		roleDao: dao.NewRoleDao(),
+	}
+}
+
+// Roles returns the global role of a user plus their role in the room, if roomId is set
+func (s *PermissionService) Roles(ctx context.Context, userId, roomId uint) ([]string, error) {
+	user, err := s.userDao.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, gerror.New("User not found")
+	}
+
+	roles := []string{consts.RoleUser}
+	if user.Role != "" {
+		roles[0] = user.Role
+	}
+
+	if roomId > 0 {
+		roomRole, err := s.roomDao.GetMemberRole(ctx, roomId, userId)
+		if err != nil {
+			return nil, err
+		}
+		if roomRole != "" {
+			roles = append(roles, roomRole)
+		}
+	}
+	return roles, nil
+}
+
+// Can reports whether a user has a permission, globally or within a room
+func (s *PermissionService) Can(ctx context.Context, userId, roomId uint, permission string) (bool, error) {
+	roles, err := s.Roles(ctx, userId, roomId)
+	if err != nil {
+		return false, err
+	}
+	return s.roleDao.HasPermission(ctx, roles, permission)
+}
+
+// Check returns an error unless the user has the permission. Users lacking a
+// room permission because they are not members get consts.ErrNotInRoom.
+func (s *PermissionService) Check(ctx context.Context, userId, roomId uint, permission string) error {
+	roles, err := s.Roles(ctx, userId, roomId)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.roleDao.HasPermission(ctx, roles, permission)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	// Only the global role was resolved, so the user is not in the room
+	if roomId > 0 && len(roles) == 1 {
+		return gerror.New(consts.ErrNotInRoom)
+	}
+	return gerror.New(consts.ErrPermissionDenied)
+}
+
+// roleRanks orders roles by authority for moderation decisions
+var roleRanks = map[string]int{
+	consts.RoleAdmin:         4,
+	consts.RoomRoleOwner:     3,
+	consts.RoomRoleModerator: 2,
+	consts.RoomRoleMember:    1,
+}
+
+// Rank returns the highest authority rank of a user within a room
+func (s *PermissionService) Rank(ctx context.Context, userId, roomId uint) (int, error) {
+	roles, err := s.Roles(ctx, userId, roomId)
+	if err != nil {
+		return 0, err
+	}
+
+	rank := 0
+	for _, role := range roles {
+		if roleRanks[role] > rank {
+			rank = roleRanks[role]
+		}
+	}
+	return rank, nil
+}
+
+// CheckModerate returns an error unless the actor has the permission and
+// outranks the target, so moderators cannot act against owners or each other
+func (s *PermissionService) CheckModerate(ctx context.Context, actorId, targetId, roomId uint, permission string) error {
+	if err := s.Check(ctx, actorId, roomId, permission); err != nil {
+		return err
+	}
+	if actorId == targetId {
+		return gerror.New("You cannot moderate yourself")
+	}
+
+	actorRank, err := s.Rank(ctx, actorId, roomId)
+	if err != nil {
+		return err
+	}
+	targetRank, err := s.Rank(ctx, targetId, roomId)
+	if err != nil {
+		return err
+	}
+	if targetRank >= actorRank {
+		return gerror.New(consts.ErrPermissionDenied)
+	}
+	return nil
+}
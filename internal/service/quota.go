@@ -0,0 +1,199 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"fmt"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gmlock"
+)
+
+// codeQuotaExceeded is returned when an upload would exceed a storage quota
+var codeQuotaExceeded = gcode.New(consts.CodeQuotaExceeded, "Quota Exceeded", nil)
+
+// QuotaService enforces the storage quotas of users and rooms and reports storage usage.
+// Unfinished resumable uploads count against the quotas, as they reserve space for their files.
+type QuotaService struct {
+	attachmentDao *dao.AttachmentDao
+	uploadDao     *dao.UploadDao
+	roomDao       *dao.ChatRoomDao
+	permService   *PermissionService
+}
+
+// NewQuotaService creates a new QuotaService instance
+func NewQuotaService() *QuotaService {
+	return &QuotaService{
+		attachmentDao: dao.NewAttachmentDao(),
+		uploadDao:     dao.NewUploadDao(),
+		roomDao:       dao.NewChatRoomDao(),
+		permService:   NewPermissionService(),
+	}
+}
+
+// UserQuota returns the bytes of attachments a user may own, 0 if unlimited
+func (s *QuotaService) UserQuota(ctx context.Context) int64 {
+	return g.Cfg().MustGet(ctx, "storage.userQuota", consts.DefaultStorageUserQuota).Int64()
+}
+
+// RoomQuota returns the bytes of attachments a room may hold, 0 if unlimited
+func (s *QuotaService) RoomQuota(ctx context.Context) int64 {
+	return g.Cfg().MustGet(ctx, "storage.roomQuota", consts.DefaultStorageRoomQuota).Int64()
+}
+
+// Check returns an error with code consts.CodeQuotaExceeded if storing size more bytes uploaded
+// by a user to a room would exceed the quota of the user or the room
+func (s *QuotaService) Check(ctx context.Context, userId, roomId uint, size int64) error {
+	if quota := s.UserQuota(ctx); quota > 0 {
+		usage, err := s.getUserUsage(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if usage.Bytes+usage.Pending+size > quota {
+			return gerror.NewCodef(codeQuotaExceeded, "Storage quota exceeded, you are using %d of %d bytes", usage.Bytes+usage.Pending, quota)
+		}
+	}
+	if quota := s.RoomQuota(ctx); quota > 0 {
+		usage, err := s.getRoomUsage(ctx, roomId)
+		if err != nil {
+			return err
+		}
+		if usage.Bytes+usage.Pending+size > quota {
+			return gerror.NewCodef(codeQuotaExceeded, "Storage quota of the chat room exceeded, %d of %d bytes are in use", usage.Bytes+usage.Pending, quota)
+		}
+	}
+	return nil
+}
+
+// Reserve checks that size more bytes fit the quotas of the user and the room like Check and
+// then calls record, which stores the attachment or upload taking up the bytes. The quotas of
+// the user and the room are locked meanwhile, so concurrent uploads cannot exceed them together.
+func (s *QuotaService) Reserve(ctx context.Context, userId, roomId uint, size int64, record func() error) error {
+	// Locks are always taken user first, so two reservations cannot wait for each other
+	userKey := fmt.Sprintf("quota:user:%d", userId)
+	roomKey := fmt.Sprintf("quota:room:%d", roomId)
+	gmlock.Lock(userKey)
+	defer gmlock.Unlock(userKey)
+	gmlock.Lock(roomKey)
+	defer gmlock.Unlock(roomKey)
+
+	if err := s.Check(ctx, userId, roomId, size); err != nil {
+		return err
+	}
+	return record()
+}
+
+// UserUsage returns the storage usage of a user
+func (s *QuotaService) UserUsage(ctx context.Context, userId uint) (*chat.StorageUsageRes, error) {
+	usage, err := s.getUserUsage(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.StorageUsageRes{Usage: usage}, nil
+}
+
+// RoomUsage returns the storage usage of a room to its owner or a global admin
+func (s *QuotaService) RoomUsage(ctx context.Context, userId, roomId uint) (*chat.RoomStorageUsageRes, error) {
+	room, err := s.roomDao.GetByID(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, gerror.New("Chat room not found")
+	}
+	if err := s.permService.Check(ctx, userId, roomId, consts.PermStorageView); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.getRoomUsage(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.RoomStorageUsageRes{Usage: usage}, nil
+}
+
+// Report returns the total storage usage and the users and rooms taking the most space.
+// Only global admins may view it.
+func (s *QuotaService) Report(ctx context.Context, userId uint, req *chat.StorageReportReq) (*chat.StorageReportRes, error) {
+	if err := s.permService.Check(ctx, userId, 0, consts.PermStorageReport); err != nil {
+		return nil, err
+	}
+
+	total, err := s.attachmentDao.GetTotalUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.uploadDao.SumTotalPendingSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.attachmentDao.ListTopUsers(ctx, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	rooms, err := s.attachmentDao.ListTopRooms(ctx, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat.StorageReportRes{
+		Total: &chat.StorageUsage{Files: total.Files, Bytes: total.Bytes, Pending: pending},
+		Users: toStorageConsumers(users, s.UserQuota(ctx)),
+		Rooms: toStorageConsumers(rooms, s.RoomQuota(ctx)),
+	}, nil
+}
+
+// getUserUsage returns the stored and pending bytes of a user against their quota
+func (s *QuotaService) getUserUsage(ctx context.Context, userId uint) (*chat.StorageUsage, error) {
+	stored, err := s.attachmentDao.GetUserUsage(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.uploadDao.SumPendingSize(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.StorageUsage{
+		Files:   stored.Files,
+		Bytes:   stored.Bytes,
+		Pending: pending,
+		Quota:   s.UserQuota(ctx),
+	}, nil
+}
+
+// getRoomUsage returns the stored and pending bytes of a room against its quota
+func (s *QuotaService) getRoomUsage(ctx context.Context, roomId uint) (*chat.StorageUsage, error) {
+	stored, err := s.attachmentDao.GetRoomUsage(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.uploadDao.SumPendingSizeByRoom(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	return &chat.StorageUsage{
+		Files:   stored.Files,
+		Bytes:   stored.Bytes,
+		Pending: pending,
+		Quota:   s.RoomQuota(ctx),
+	}, nil
+}
+
+// toStorageConsumers converts storage usages to report entries
+func toStorageConsumers(usages []dao.StorageUsage, quota int64) []chat.StorageConsumer {
+	consumers := make([]chat.StorageConsumer, 0, len(usages))
+	for _, usage := range usages {
+		consumers = append(consumers, chat.StorageConsumer{
+			Id:    usage.Id,
+			Name:  usage.Name,
+			Files: usage.Files,
+			Bytes: usage.Bytes,
+			Quota: quota,
+		})
+	}
+	return consumers
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+// tokenCleanupInterval is how often expired refresh tokens are deleted
+const tokenCleanupInterval = time.Hour
+
+// TokenPair is a short-lived access token together with its rotating refresh token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // Access token lifetime in seconds
+}
+
+// IssueTokens starts a new login session and issues its first token pair
+func (s *JwtService) IssueTokens(ctx context.Context, user *entity.User) (*TokenPair, error) {
+	pair, _, err := s.issue(ctx, user, guid.S())
+	return pair, err
+}
+
+// RefreshTokens rotates a refresh token and issues a new token pair for the same session.
+// Presenting an already rotated refresh token revokes the whole session, since it
+// means the token has been stolen and used by someone else.
+func (s *JwtService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.tokenDao.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.ExpiresAt.Before(time.Now()) {
+		return nil, gerror.NewCode(gcode.CodeNotAuthorized, "Invalid or expired refresh token")
+	}
+	if stored.Revoked {
+		if stored.ReplacedBy != 0 {
+			return nil, s.revokeReusedSession(ctx, stored.SessionId)
+		}
+		return nil, gerror.NewCode(gcode.CodeNotAuthorized, "Refresh token has been revoked")
+	}
+
+	user, err := dao.NewUserDao().GetByID(ctx, stored.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, gerror.NewCode(gcode.CodeNotAuthorized, "User not found")
+	}
+
+	// The new pair only survives if this request is the one that rotates the token; a
+	// concurrent refresh with the same token finds it revoked and counts as reuse
+	var pair *TokenPair
+	reused := false
+	err = g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		var newId uint
+		var err error
+		if pair, newId, err = s.issue(ctx, user, stored.SessionId); err != nil {
+			return err
+		}
+		rotated, err := s.tokenDao.Rotate(ctx, stored.Id, newId)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			// Rolls back the new pair
+			reused = true
+			return gerror.New("Refresh token was already rotated")
+		}
+		return nil
+	})
+	if reused {
+		return nil, s.revokeReusedSession(ctx, stored.SessionId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// revokeReusedSession revokes a session whose refresh token was presented again after being
+// rotated, since it means the token has been stolen and used by someone else
+func (s *JwtService) revokeReusedSession(ctx context.Context, sessionId string) error {
+	g.Log().Warning(ctx, "Refresh token reuse detected, revoking session:", sessionId)
+	if err := s.RevokeSession(ctx, sessionId); err != nil {
+		return err
+	}
+	return gerror.NewCode(gcode.CodeNotAuthorized, "Refresh token has been revoked")
+}
+
+// ValidateToken parses an access token and checks that it has not been revoked
+func (s *JwtService) ValidateToken(ctx context.Context, tokenString string) (*JwtClaims, error) {
+	claims, err := s.ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, gerror.New("Token has no ID")
+	}
+
+	active, err := s.tokenDao.IsAccessTokenActive(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, gerror.New("Token has been revoked")
+	}
+	return claims, nil
+}
+
+// RevokeSession revokes all tokens of a login session and closes its WebSocket connections
+func (s *JwtService) RevokeSession(ctx context.Context, sessionId string) error {
+	if err := s.tokenDao.RevokeSession(ctx, sessionId); err != nil {
+		return err
+	}
+	GetWebSocketManager().CloseSessionConnections(sessionId)
+	return nil
+}
+
+// RevokeUser revokes all tokens of a user and closes all of their WebSocket connections
+func (s *JwtService) RevokeUser(ctx context.Context, userId uint) error {
+	if err := s.tokenDao.RevokeUser(ctx, userId); err != nil {
+		return err
+	}
+	GetWebSocketManager().CloseUserConnections(userId)
+	return nil
+}
+
+// DeleteExpiredTokens deletes the refresh tokens that have expired. Rotated tokens are kept
+// until then, so presenting one again is still detected as reuse.
+func (s *JwtService) DeleteExpiredTokens(ctx context.Context) error {
+	deleted, err := s.tokenDao.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		g.Log().Info(ctx, "Deleted expired refresh tokens:", deleted)
+	}
+	return nil
+}
+
+// StartCleanup periodically deletes expired refresh tokens in the background
+func (s *JwtService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(tokenCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.DeleteExpiredTokens(ctx); err != nil {
+				g.Log().Error(ctx, "Delete expired refresh tokens failed:", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// issue generates and persists a token pair for the given session
+func (s *JwtService) issue(ctx context.Context, user *entity.User, sessionId string) (*TokenPair, uint, error) {
+	jti := guid.S()
+	accessToken, err := s.GenerateToken(user, sessionId, jti)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	id, err := s.tokenDao.Create(ctx, &entity.RefreshToken{
+		UserId:    user.Id,
+		TokenHash: hashToken(refreshToken),
+		SessionId: sessionId,
+		AccessJti: jti,
+		ExpiresAt: time.Now().Add(s.refreshExpire),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessExpire / time.Second),
+	}, id, nil
+}
+
+// newRefreshToken generates a random opaque refresh token
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 hash under which a refresh token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
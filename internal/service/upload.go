@@ -0,0 +1,331 @@
+package service
+
+import (
+	"chatroom/api/chat"
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"chatroom/internal/model/entity"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gmlock"
+	"github.com/gogf/gf/v2/util/grand"
+)
+
+// Upload errors carry the HTTP status the tus endpoint answers with as their code
+var (
+	codeUploadInvalid   = gcode.New(http.StatusBadRequest, "Bad Request", nil)
+	codeUploadForbidden = gcode.New(http.StatusForbidden, "Forbidden", nil)
+	codeUploadNotFound  = gcode.New(http.StatusNotFound, "Not Found", nil)
+	codeUploadConflict  = gcode.New(http.StatusConflict, "Conflict", nil)
+	codeUploadGone      = gcode.New(http.StatusGone, "Gone", nil)
+	codeUploadTooLarge  = gcode.New(http.StatusRequestEntityTooLarge, "Request Entity Too Large", nil)
+	codeUploadLocked    = gcode.New(http.StatusLocked, "Locked", nil)
+)
+
+// uploadCleanupInterval is how often expired uploads are discarded
+const uploadCleanupInterval = 10 * time.Minute
+
+// UploadService handles resumable uploads. Received bytes are appended to a partial file; once
+// all bytes arrived the file becomes an attachment and is sent to its room as a file message.
+type UploadService struct {
+	uploadDao      *dao.UploadDao
+	userDao        *dao.UserDao
+	roomDao        *dao.ChatRoomDao
+	messageService *MessageService
+}
+
+// NewUploadService creates a new UploadService instance
+func NewUploadService() *UploadService {
+	return &UploadService{
+		uploadDao:      dao.NewUploadDao(),
+		userDao:        dao.NewUserDao(),
+		roomDao:        dao.NewChatRoomDao(),
+		messageService: NewMessageService(),
+	}
+}
+
+// MaxSize returns the maximum size of an upload in bytes, 0 if unlimited
+func (s *UploadService) MaxSize(ctx context.Context) int64 {
+	return g.Cfg().MustGet(ctx, "upload.maxSize", consts.DefaultUploadMaxSize).Int64()
+}
+
+// Create starts an upload of a file to a room after checking the size limits and the user's quota
+func (s *UploadService) Create(ctx context.Context, userId, roomId uint, name string, size int64) (*entity.Upload, error) {
+	if size <= 0 {
+		return nil, gerror.NewCode(codeUploadInvalid, "File is empty")
+	}
+	if maxSize := s.MaxSize(ctx); maxSize > 0 && size > maxSize {
+		return nil, gerror.NewCodef(codeUploadTooLarge, "File is too large, the limit is %d bytes", maxSize)
+	}
+	if err := s.messageService.checkUpload(ctx, userId, roomId); err != nil {
+		return nil, gerror.WrapCode(codeUploadForbidden, err)
+	}
+
+	upload := &entity.Upload{
+		Id:        grand.S(32),
+		UserId:    userId,
+		RoomId:    roomId,
+		Name:      attachmentName(name),
+		Size:      size,
+		ExpiresAt: s.expiresAt(ctx),
+	}
+	err := s.messageService.quotaService.Reserve(ctx, userId, roomId, size, func() error {
+		// The quota covers uploads in progress, which occupy space until they complete or expire
+		quota := g.Cfg().MustGet(ctx, "upload.userQuota", consts.DefaultUploadUserQuota).Int64()
+		pending, err := s.uploadDao.SumPendingSize(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if quota > 0 && pending+size > quota {
+			return gerror.NewCodef(codeUploadTooLarge, "Upload quota exceeded, %d of %d bytes are in use by unfinished uploads", pending, quota)
+		}
+
+		if err := os.MkdirAll(partialDir(ctx), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(partialPath(ctx, upload.Id), nil, 0o644); err != nil {
+			return err
+		}
+		if err := s.uploadDao.Create(ctx, upload); err != nil {
+			deletePartialFiles(ctx, upload.Id)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		// Storage quota errors answer with the same status as the other size limits
+		if gerror.Code(err) == codeQuotaExceeded {
+			return nil, gerror.WrapCode(codeUploadTooLarge, err)
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Get returns an upload of a user that has not expired
+func (s *UploadService) Get(ctx context.Context, userId uint, id string) (*entity.Upload, error) {
+	upload, err := s.uploadDao.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil || upload.UserId != userId {
+		return nil, gerror.NewCode(codeUploadNotFound, "Upload not found")
+	}
+	if !upload.ExpiresAt.After(time.Now()) {
+		return nil, gerror.NewCode(codeUploadGone, "Upload has expired")
+	}
+	return upload, nil
+}
+
+// Append writes bytes received at an offset to an upload and completes it once all bytes arrived.
+// The offset must match the bytes received so far. On a broken connection the bytes that did
+// arrive are kept, so the client can resume from the returned offset.
+func (s *UploadService) Append(ctx context.Context, userId uint, id string, offset int64, content io.Reader) (*entity.Upload, error) {
+	// Concurrent requests for the same upload would interleave their bytes
+	lockKey := "upload:" + id
+	if !gmlock.TryLock(lockKey) {
+		return nil, gerror.NewCode(codeUploadLocked, "Upload is being written by another request")
+	}
+	defer gmlock.Unlock(lockKey)
+
+	upload, err := s.Get(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return nil, gerror.NewCodef(codeUploadConflict, "Upload-Offset %d does not match the %d bytes received", offset, upload.Offset)
+	}
+
+	n, err := s.writePartial(ctx, upload, content)
+	if n > 0 {
+		upload.Offset += n
+		upload.ExpiresAt = s.expiresAt(ctx)
+		if err := s.uploadDao.UpdateOffset(ctx, upload.Id, upload.Offset, upload.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return upload, err
+	}
+
+	if upload.Offset == upload.Size {
+		if err := s.complete(ctx, upload); err != nil {
+			return upload, err
+		}
+	}
+	return upload, nil
+}
+
+// writePartial writes received bytes to the partial file of an upload at its offset.
+// Bytes beyond the declared size are not accepted.
+func (s *UploadService) writePartial(ctx context.Context, upload *entity.Upload, content io.Reader) (int64, error) {
+	file, err := os.OpenFile(partialPath(ctx, upload.Id), os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return 0, gerror.NewCode(codeUploadNotFound, "Upload not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// Bytes written after the last recorded offset, e.g. before a crash, are discarded
+	if err := file.Truncate(upload.Offset); err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(file, io.LimitReader(content, upload.Size-upload.Offset))
+	if err != nil {
+		return n, err
+	}
+	return n, file.Sync()
+}
+
+// complete turns a fully received upload into an attachment and sends it to its room as a file message
+func (s *UploadService) complete(ctx context.Context, upload *entity.Upload) error {
+	// Membership or mutes may have changed while uploading
+	if err := s.messageService.checkUpload(ctx, upload.UserId, upload.RoomId); err != nil {
+		s.delete(ctx, upload.Id)
+		return gerror.WrapCode(codeUploadForbidden, err)
+	}
+
+	file, err := os.Open(partialPath(ctx, upload.Id))
+	if err != nil {
+		return err
+	}
+	attachment, _, err := s.messageService.storeAttachment(ctx, upload.UserId, upload.RoomId, upload.Name, upload.Size, file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	// The nonce makes a retried completion return the message of the first one
+	msg := &chat.MessageReq{
+		Type:         consts.MessageTypeFile,
+		RoomId:       upload.RoomId,
+		AttachmentId: attachment.Id,
+		Nonce:        "upload:" + upload.Id,
+	}
+	message, created, err := s.messageService.CreateMessage(ctx, upload.UserId, msg)
+	if err != nil || !created {
+		// Nothing references the attachment stored by this attempt, so it would only use up quota
+		if deleteErr := s.messageService.deleteAttachment(ctx, attachment.Id); deleteErr != nil {
+			g.Log().Error(ctx, "Delete attachment of uploaded file failed:", attachment.Id, deleteErr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	s.delete(ctx, upload.Id)
+	if !created {
+		return nil
+	}
+
+	// The message is stored, so delivery problems no longer fail the upload
+	sender, err := s.userDao.GetByID(ctx, upload.UserId)
+	if err != nil || sender == nil {
+		g.Log().Error(ctx, "Load sender of uploaded file failed:", upload.UserId, err)
+		return nil
+	}
+	room, err := s.roomDao.GetByID(ctx, upload.RoomId)
+	if err != nil || room == nil {
+		g.Log().Error(ctx, "Load room of uploaded file failed:", upload.RoomId, err)
+		return nil
+	}
+	if err := s.messageService.deliverMessage(ctx, sender, room.Type == consts.RoomTypeDirect, msg, message); err != nil {
+		g.Log().Error(ctx, "Deliver uploaded file failed:", message.Id, err)
+	}
+	return nil
+}
+
+// Terminate discards an upload of a user
+func (s *UploadService) Terminate(ctx context.Context, userId uint, id string) error {
+	lockKey := "upload:" + id
+	if !gmlock.TryLock(lockKey) {
+		return gerror.NewCode(codeUploadLocked, "Upload is being written by another request")
+	}
+	defer gmlock.Unlock(lockKey)
+
+	upload, err := s.uploadDao.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if upload == nil || upload.UserId != userId {
+		return gerror.NewCode(codeUploadNotFound, "Upload not found")
+	}
+	s.delete(ctx, upload.Id)
+	return nil
+}
+
+// DeleteExpired discards the uploads that expired before completing
+func (s *UploadService) DeleteExpired(ctx context.Context) error {
+	ids, err := s.uploadDao.ListExpiredIds(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.delete(ctx, id)
+	}
+	if len(ids) > 0 {
+		g.Log().Info(ctx, "Deleted expired uploads:", len(ids))
+	}
+	return nil
+}
+
+// StartCleanup periodically discards expired uploads in the background
+func (s *UploadService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(uploadCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.DeleteExpired(ctx); err != nil {
+				g.Log().Error(ctx, "Delete expired uploads failed:", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// delete removes an upload and its partial file; failures are only logged
+func (s *UploadService) delete(ctx context.Context, id string) {
+	if err := s.uploadDao.Delete(ctx, id); err != nil {
+		g.Log().Error(ctx, "Delete upload failed:", id, err)
+		return
+	}
+	deletePartialFiles(ctx, id)
+}
+
+// expiresAt returns the expiry of an upload that received bytes now
+func (s *UploadService) expiresAt(ctx context.Context) time.Time {
+	expire := g.Cfg().MustGet(ctx, "upload.expire", consts.DefaultUploadExpire).Int64()
+	return time.Now().Add(time.Duration(expire) * time.Second)
+}
+
+// partialDir returns the directory holding the partial files of uploads in progress
+func partialDir(ctx context.Context) string {
+	return g.Cfg().MustGet(ctx, "upload.partialPath", "uploads/partial").String()
+}
+
+// partialPath returns the path of the partial file of an upload
+func partialPath(ctx context.Context, id string) string {
+	return filepath.Join(partialDir(ctx), id)
+}
+
+// deletePartialFiles deletes the partial files of uploads; failures are only logged
+func deletePartialFiles(ctx context.Context, ids ...string) {
+	for _, id := range ids {
+		if err := os.Remove(partialPath(ctx, id)); err != nil && !os.IsNotExist(err) {
+			g.Log().Error(ctx, "Delete partial upload failed:", id, err)
+		}
+	}
+}
@@ -66,9 +66,9 @@ func (s *UserService) Login(ctx context.Context, req *user.LoginReq) (*user.Logi
 		return nil, gerror.New("Invalid username or password")
 	}
 
-	// Generate JWT token
+	// Generate access and refresh tokens
 	jwtService := NewJwtService()
-	token, err := jwtService.GenerateToken(u)
+	tokens, err := jwtService.IssueTokens(ctx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -80,14 +80,49 @@ func (s *UserService) Login(ctx context.Context, req *user.LoginReq) (*user.Logi
 	}
 
 	return &user.LoginRes{
-		Token:    token,
-		Id:       u.Id,
-		Username: u.Username,
-		Nickname: u.Nickname,
-		Avatar:   u.Avatar,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		Id:           u.Id,
+		Username:     u.Username,
+		Nickname:     u.Nickname,
+		Avatar:       u.Avatar,
+	}, nil
+}
+
+// Refresh rotates a refresh token and returns a new token pair
+func (s *UserService) Refresh(ctx context.Context, req *user.RefreshReq) (*user.RefreshRes, error) {
+	jwtService := NewJwtService()
+	tokens, err := jwtService.RefreshTokens(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.RefreshRes{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	}, nil
 }
 
+// Logout revokes the tokens of the current login session
+func (s *UserService) Logout(ctx context.Context, sessionId string) (*user.LogoutRes, error) {
+	jwtService := NewJwtService()
+	if err := jwtService.RevokeSession(ctx, sessionId); err != nil {
+		return nil, err
+	}
+	return &user.LogoutRes{Success: true}, nil
+}
+
+// LogoutAll revokes the tokens of every login session of a user
+func (s *UserService) LogoutAll(ctx context.Context, userId uint) (*user.LogoutAllRes, error) {
+	jwtService := NewJwtService()
+	if err := jwtService.RevokeUser(ctx, userId); err != nil {
+		return nil, err
+	}
+	return &user.LogoutAllRes{Success: true}, nil
+}
+
 // GetProfile retrieves user profile
 func (s *UserService) GetProfile(ctx context.Context, userId uint) (*user.ProfileRes, error) {
 	u, err := s.userDao.GetByID(ctx, userId)
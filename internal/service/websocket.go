@@ -1,49 +1,73 @@
 package service
 
 import (
+	"chatroom/api/chat"
 	"chatroom/internal/consts"
 	"chatroom/internal/dao"
 	"chatroom/internal/model/entity"
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gorilla/websocket"
 )
 
-// WebSocketManager manages all WebSocket connections
+// WebSocketManager manages all WebSocket connections. Each connection subscribes to any number
+// of rooms, and a user may be subscribed to the same room several times, e.g. from several
+// devices or browser tabs.
 type WebSocketManager struct {
-	connections sync.Map // map[roomID]*sync.Map(map[userID]*Connection)
+	sockets     sync.Map     // map[connectionID]*Connection of all live connections
+	connections sync.Map     // map[roomID]*sync.Map(map[connectionID]*Connection) of the subscribed connections
+	presence    map[uint]int // Number of live connections per user, guarded by mu
+	mu          sync.Mutex   // Serializes registering and removing connections
+	lastConnId  atomic.Uint64
 	upgrader    websocket.Upgrader
 	userDao     *dao.UserDao
+	permService *PermissionService
 }
 
 // Connection represents a WebSocket connection
 type Connection struct {
-	conn     *websocket.Conn
-	user     *entity.User
-	roomId   uint
-	send     chan []byte
-	manager  *WebSocketManager
-	lastPing time.Time
+	id          uint64 // Unique among the live connections
+	conn        *websocket.Conn
+	user        *entity.User
+	defaultRoom uint   // Room of frames without a room ID, 0 if they are rejected
+	sessionId   string // Login session of the token used to connect
+	send        chan []byte
+	quit        chan struct{} // Closed to make writePump flush and close the connection
+	closeOnce   sync.Once
+	manager     *WebSocketManager
+	lastPing    time.Time
+	rooms       map[uint]*subscription // Subscribed rooms, guarded by roomsMu
+	roomsMu     sync.Mutex             // Acquired after the manager's mu when both are held
 }
 
 // WebSocketMessage represents a message structure for WebSocket communication
 type WebSocketMessage struct {
-	Type      int         `json:"type"`
-	Content   string      `json:"content,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
-	Timestamp string      `json:"timestamp"`
-	UserId    uint        `json:"userId,omitempty"`
-	Username  string      `json:"username,omitempty"`
-	Nickname  string      `json:"nickname,omitempty"`
-	Avatar    string      `json:"avatar,omitempty"`
+	V            int              `json:"v"`                      // Protocol version, set when encoding
+	Event        int              `json:"event"`                  // Event kind, one of consts.WsMsgType
+	RequestId    string           `json:"requestId,omitempty"`    // Client-generated ID of the request an ack or error answers
+	Type         int              `json:"type"`                   // Message type of message events
+	RoomId       uint             `json:"roomId,omitempty"`       // Room the frame belongs to
+	Id           uint             `json:"id,omitempty"`           // Message ID of stored messages, read markers and receipts
+	Seq          uint             `json:"seq,omitempty"`          // Sequence number of stored messages in their room; the last one seen when subscribing
+	ParentId     uint             `json:"parentId,omitempty"`     // Thread root of replies
+	AttachmentId uint             `json:"attachmentId,omitempty"` // Uploaded attachment sent by clients
+	Attachment   *chat.Attachment `json:"attachment,omitempty"`   // Attachment metadata delivered to clients
+	Content      string           `json:"content,omitempty"`
+	Data         interface{}      `json:"data,omitempty"`
+	Timestamp    string           `json:"timestamp"`
+	UserId       uint             `json:"userId,omitempty"`
+	Username     string           `json:"username,omitempty"`
+	Nickname     string           `json:"nickname,omitempty"`
+	Avatar       string           `json:"avatar,omitempty"`
 }
 
 var (
@@ -62,7 +86,9 @@ func GetWebSocketManager() *WebSocketManager {
 				ReadBufferSize:  1024,
 				WriteBufferSize: 1024,
 			},
-			userDao: dao.NewUserDao(),
+			presence:    make(map[uint]int),
+			userDao:     dao.NewUserDao(),
+			permService: NewPermissionService(),
 		}
 
 		// Start heartbeat checker
@@ -71,8 +97,11 @@ func GetWebSocketManager() *WebSocketManager {
 	return wsManager
 }
 
-// HandleWebSocket upgrades HTTP connection to WebSocket and handles the connection
-func (m *WebSocketManager) HandleWebSocket(r *ghttp.Request, user *entity.User, roomId uint) {
+// HandleWebSocket upgrades HTTP connection to WebSocket and handles the connection. The
+// connection subscribes to rooms with WsMsgTypeSubscribe frames; a room ID given when
+// connecting is subscribed to right away, replaying the messages after lastSeq, and used
+// for frames without a room ID.
+func (m *WebSocketManager) HandleWebSocket(r *ghttp.Request, user *entity.User, roomId, lastSeq uint, sessionId string) {
 	// Upgrade connection
 	ws, err := m.upgrader.Upgrade(r.Response.Writer, r.Request, nil)
 	if err != nil {
@@ -80,86 +109,110 @@ func (m *WebSocketManager) HandleWebSocket(r *ghttp.Request, user *entity.User,
 		return
 	}
 
+	// Only room members may connect to a room
+	if roomId > 0 {
+		if err := m.permService.Check(r.Context(), user.Id, roomId, consts.PermMessageRead); err != nil {
+			rejectConnection(ws, err.Error())
+			return
+		}
+	}
+
 	// Create new connection
 	conn := &Connection{
-		conn:     ws,
-		user:     user,
-		roomId:   roomId,
-		send:     make(chan []byte, 256),
-		manager:  m,
-		lastPing: time.Now(),
+		id:          m.lastConnId.Add(1),
+		conn:        ws,
+		user:        user,
+		defaultRoom: roomId,
+		sessionId:   sessionId,
+		send:        make(chan []byte, 256),
+		quit:        make(chan struct{}),
+		manager:     m,
+		lastPing:    time.Now(),
+		rooms:       make(map[uint]*subscription),
 	}
 
-	// Update user status to online
-	m.userDao.UpdateStatus(r.Context(), user.Id, consts.UserStatusOnline)
-
-	// Store connection
+	// Store connection; the user goes online with their first connection
 	m.registerConnection(conn)
 
 	// Start goroutines for reading and writing
 	go conn.writePump()
 	go conn.readPump()
 
-	// Send current user list to all users in the room
-	m.broadcastUserList(roomId)
-
-	// Notify other users that new user joined
-	m.broadcastToRoom(roomId, WebSocketMessage{
-		Type:      consts.WsMsgTypeJoin,
-		Content:   fmt.Sprintf("%s joined the room", user.Nickname),
-		Timestamp: time.Now().Format(time.RFC3339),
-		UserId:    user.Id,
-		Username:  user.Username,
-		Nickname:  user.Nickname,
-		Avatar:    user.Avatar,
-	})
+	if roomId > 0 {
+		if err := conn.subscribe(roomId, lastSeq); err != nil {
+			conn.closeWithError(err.Error())
+		}
+	}
 }
 
 // registerConnection registers a new WebSocket connection
 func (m *WebSocketManager) registerConnection(conn *Connection) {
-	// Get or create room connections map
-	value, _ := m.connections.LoadOrStore(conn.roomId, &sync.Map{})
-	roomConns := value.(*sync.Map)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sockets.Store(conn.id, conn)
 
-	// Store connection in room
-	roomConns.Store(conn.user.Id, conn)
+	// Update user status to online with the first connection
+	m.presence[conn.user.Id]++
+	if m.presence[conn.user.Id] == 1 {
+		m.userDao.UpdateStatus(context.Background(), conn.user.Id, consts.UserStatusOnline)
+	}
 }
 
-// removeConnection removes a WebSocket connection
+// removeConnection removes a WebSocket connection and its subscriptions. It may be called
+// several times for the same connection; only the first call has an effect.
 func (m *WebSocketManager) removeConnection(conn *Connection) {
-	if value, ok := m.connections.Load(conn.roomId); ok {
-		roomConns := value.(*sync.Map)
-		roomConns.Delete(conn.user.Id)
+	m.mu.Lock()
+	if _, loaded := m.sockets.LoadAndDelete(conn.id); !loaded {
+		m.mu.Unlock()
+		return
+	}
 
-		// Update user status to offline
+	// Update user status to offline once their last connection closed
+	m.presence[conn.user.Id]--
+	if m.presence[conn.user.Id] <= 0 {
+		delete(m.presence, conn.user.Id)
 		m.userDao.UpdateStatus(context.Background(), conn.user.Id, consts.UserStatusOffline)
+	}
 
-		// If room is empty, remove it from connections map
-		empty := true
-		roomConns.Range(func(key, value interface{}) bool {
-			empty = false
-			return false
-		})
-		if empty {
-			m.connections.Delete(conn.roomId)
-		}
+	conn.roomsMu.Lock()
+	subs := conn.rooms
+	conn.rooms = make(map[uint]*subscription)
+	conn.roomsMu.Unlock()
+	for roomId := range subs {
+		m.removeFromRoom(conn, roomId)
+	}
+	m.mu.Unlock()
+
+	for roomId, sub := range subs {
+		// Users disconnecting mid-typing stop typing
+		conn.stopTyping(sub)
 
 		// Broadcast updated user list
-		m.broadcastUserList(conn.roomId)
+		m.broadcastUserList(roomId)
 	}
 }
 
+// hasUserConnection reports whether a user has a connection among the connections of a room
+func hasUserConnection(roomConns *sync.Map, userId uint) bool {
+	found := false
+	roomConns.Range(func(key, value interface{}) bool {
+		found = value.(*Connection).user.Id == userId
+		return !found
+	})
+	return found
+}
+
 // broadcastToRoom broadcasts a message to all users in a room
 func (m *WebSocketManager) broadcastToRoom(roomId uint, msg WebSocketMessage) {
 	if value, ok := m.connections.Load(roomId); ok {
 		roomConns := value.(*sync.Map)
+		msg.RoomId = roomId
 		msgBytes, _ := json.Marshal(msg)
 
 		roomConns.Range(func(key, value interface{}) bool {
 			conn := value.(*Connection)
-			select {
-			case conn.send <- msgBytes:
-			default:
+			if !conn.deliver(roomId, msg.Seq, msgBytes) {
 				m.removeConnection(conn)
 			}
 			return true
@@ -180,12 +233,19 @@ func (m *WebSocketManager) broadcastUserList(roomId uint) {
 			return
 		}
 
+		// 在线用户可能有多个连接
+		connected := make(map[uint]bool)
+		roomConns.Range(func(key, value interface{}) bool {
+			connected[value.(*Connection).user.Id] = true
+			return true
+		})
+
 		// 构建用户列表，包含在线状态
 		users := make([]map[string]interface{}, 0)
 		for _, u := range allUsers {
 			// 检查用户是否在线
 			status := consts.UserStatusOffline
-			if _, ok := roomConns.Load(u.Id); ok {
+			if connected[u.Id] {
 				status = consts.UserStatusOnline
 			}
 
@@ -205,7 +265,7 @@ func (m *WebSocketManager) broadcastUserList(roomId uint) {
 
 		// 广播用户列表
 		m.broadcastToRoom(roomId, WebSocketMessage{
-			Type:      consts.WsMsgTypeUserList,
+			Event:     consts.WsMsgTypeUserList,
 			Data:      users,
 			Timestamp: time.Now().Format(time.RFC3339),
 		})
@@ -218,17 +278,13 @@ func (m *WebSocketManager) heartbeatChecker() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		m.connections.Range(func(roomId, value interface{}) bool {
-			roomConns := value.(*sync.Map)
-			roomConns.Range(func(userId, connValue interface{}) bool {
-				conn := connValue.(*Connection)
-				if time.Since(conn.lastPing) > 90*time.Second {
-					// Connection is stale, close it
-					conn.conn.Close()
-					m.removeConnection(conn)
-				}
-				return true
-			})
+		m.sockets.Range(func(connId, value interface{}) bool {
+			conn := value.(*Connection)
+			if time.Since(conn.lastPing) > 90*time.Second {
+				// Connection is stale, close it
+				conn.conn.Close()
+				m.removeConnection(conn)
+			}
 			return true
 		})
 	}
@@ -263,6 +319,9 @@ func (c *Connection) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+		case <-c.quit:
+			c.flushAndClose()
+			return
 		}
 	}
 }
@@ -294,31 +353,55 @@ func (c *Connection) readPump() {
 		// Update last ping time
 		c.lastPing = time.Now()
 
-		// Handle message
-		var wsMsg WebSocketMessage
-		if err := json.Unmarshal(message, &wsMsg); err != nil {
-			continue
-		}
+		c.handleFrame(message)
+	}
+}
 
-		// Add user and timestamp information
-		wsMsg.UserId = c.user.Id
-		wsMsg.Username = c.user.Username
-		wsMsg.Nickname = c.user.Nickname
-		wsMsg.Avatar = c.user.Avatar
-		wsMsg.Timestamp = time.Now().Format(time.RFC3339)
-
-		// Store message in database if it's not a system message
-		if wsMsg.Type != consts.MessageTypeSystem {
-			messageDao := dao.NewMessageDao()
-			messageDao.Create(context.Background(), &entity.Message{
-				RoomId:  c.roomId,
-				UserId:  c.user.Id,
-				Content: wsMsg.Content,
-				Type:    wsMsg.Type,
-			})
-		}
+// sendChatMessage stores a message sent to a subscribed room and delivers it. Rejected messages,
+// e.g. from muted members, are only reported to the sender. It returns the acknowledgement with
+// the ID and sequence number of the stored message; a repeated send with the same nonce is only
+// acknowledged with those of the original message.
+func (c *Connection) sendChatMessage(ctx context.Context, sub *subscription, frame *clientFrame) (*WebSocketMessage, error) {
+	req := &chat.MessageReq{
+		Type:         frame.Type,
+		Content:      frame.Content,
+		RoomId:       sub.roomId,
+		ParentId:     frame.ParentId,
+		AttachmentId: frame.AttachmentId,
+		Nonce:        frame.Nonce,
+	}
+	messageService := NewMessageService()
+	stored, created, err := messageService.CreateMessage(ctx, c.user.Id, req)
+	if err != nil {
+		return nil, err
+	}
+	ack := &WebSocketMessage{RoomId: sub.roomId, Id: stored.Id, Seq: stored.Seq}
+	if !created {
+		return ack, nil
+	}
+	if err := messageService.deliverMessage(ctx, c.user, sub.direct, req, stored); err != nil {
+		return nil, err
+	}
+
+	// Sending a message ends typing it
+	c.stopTyping(sub)
+	return ack, nil
+}
 
-		// Broadcast message
-		c.manager.broadcastToRoom(c.roomId, wsMsg)
+// handleAction handles frames that act on existing messages of a subscribed room or signal
+// typing in it rather than sending a message
+func (c *Connection) handleAction(ctx context.Context, sub *subscription, frame *clientFrame) error {
+	switch frame.Event {
+	case consts.WsMsgTypeRead:
+		return NewMessageService().MarkRead(ctx, c.user.Id, sub.roomId, frame.Id)
+	case consts.WsMsgTypeReact:
+		_, err := NewMessageService().AddReaction(ctx, c.user.Id, frame.Id, frame.Content)
+		return err
+	case consts.WsMsgTypeUnreact:
+		_, err := NewMessageService().RemoveReaction(ctx, c.user.Id, frame.Id, frame.Content)
+		return err
+	case consts.WsMsgTypeTyping:
+		return c.handleTyping(sub, frame.Content)
 	}
+	return gerror.New("Unknown event")
 }
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeWriteWait is the time allowed to write the final frames before closing
+const closeWriteWait = 5 * time.Second
+
+// CloseSessionConnections closes all live connections opened with tokens of a login session
+func (m *WebSocketManager) CloseSessionConnections(sessionId string) {
+	m.closeConnections("Session has been revoked", func(conn *Connection) bool {
+		return conn.sessionId == sessionId
+	})
+}
+
+// CloseUserConnections closes all live connections of a user
+func (m *WebSocketManager) CloseUserConnections(userId uint) {
+	m.closeConnections("Session has been revoked", func(conn *Connection) bool {
+		return conn.user.Id == userId
+	})
+}
+
+// closeConnections closes every connection matching the filter with an error frame.
+// Closing the socket makes readPump exit, which removes the connection.
+func (m *WebSocketManager) closeConnections(reason string, match func(conn *Connection) bool) {
+	m.sockets.Range(func(connId, value interface{}) bool {
+		if conn := value.(*Connection); match(conn) {
+			conn.closeWithError(reason)
+		}
+		return true
+	})
+}
+
+// closeWithError queues an error frame and makes writePump close the connection after sending it
+func (c *Connection) closeWithError(reason string) {
+	c.closeOnce.Do(func() {
+		c.sendError(0, reason)
+		close(c.quit)
+	})
+}
+
+// flushAndClose writes the queued messages followed by a policy violation close frame.
+// It must only be called from writePump.
+func (c *Connection) flushAndClose() {
+	c.conn.SetWriteDeadline(time.Now().Add(closeWriteWait))
+	for {
+		select {
+		case message := <-c.send:
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		default:
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ""))
+			return
+		}
+	}
+}
+
+// sendError queues an error frame about a room, 0 for the connection itself, for the connection
+// only, dropping it if the send buffer is full
+func (c *Connection) sendError(roomId uint, content string) {
+	select {
+	case c.send <- errorFrame(roomId, content):
+	default:
+	}
+}
+
+// rejectConnection sends an error frame on a freshly upgraded socket and closes it
+func rejectConnection(ws *websocket.Conn, reason string) {
+	defer ws.Close()
+	ws.SetWriteDeadline(time.Now().Add(closeWriteWait))
+	if err := ws.WriteMessage(websocket.TextMessage, errorFrame(0, reason)); err != nil {
+		return
+	}
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ""))
+}
+
+// errorFrame encodes a WsMsgTypeError message
+func errorFrame(roomId uint, content string) []byte {
+	msg, _ := json.Marshal(WebSocketMessage{
+		Event:     consts.WsMsgTypeError,
+		RoomId:    roomId,
+		Content:   content,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return msg
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// deliverDirect broadcasts a message to the connections of a direct conversation and
+// forwards it as a WsMsgTypeDirect frame to members connected to other rooms only
+func (m *WebSocketManager) deliverDirect(ctx context.Context, roomId uint, msg WebSocketMessage) {
+	m.broadcastToRoom(roomId, msg)
+
+	members, err := dao.NewChatRoomDao().ListMembers(ctx, roomId)
+	if err != nil {
+		g.Log().Error(ctx, "List direct conversation members failed:", err)
+		return
+	}
+
+	direct := msg
+	direct.Event = consts.WsMsgTypeDirect
+	direct.RoomId = roomId
+	direct.Data = g.Map{
+		"roomId":      roomId,
+		"messageType": msg.Type,
+	}
+	msgBytes, _ := json.Marshal(direct)
+
+	for _, member := range members {
+		if m.isConnectedToRoom(roomId, member.UserId) {
+			continue
+		}
+		m.sendToUser(member.UserId, msgBytes)
+	}
+}
+
+// isConnectedToRoom reports whether a user has a live connection subscribed to a room
+func (m *WebSocketManager) isConnectedToRoom(roomId, userId uint) bool {
+	value, ok := m.connections.Load(roomId)
+	if !ok {
+		return false
+	}
+	return hasUserConnection(value.(*sync.Map), userId)
+}
+
+// isOnline reports whether a user has a live connection
+func (m *WebSocketManager) isOnline(userId uint) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.presence[userId] > 0
+}
+
+// sendToUser sends a message to all live connections of a user, whatever rooms or device they are on
+func (m *WebSocketManager) sendToUser(userId uint, msgBytes []byte) {
+	m.sockets.Range(func(connId, value interface{}) bool {
+		conn := value.(*Connection)
+		if conn.user.Id != userId {
+			return true
+		}
+		select {
+		case conn.send <- msgBytes:
+		default:
+		}
+		return true
+	})
+}
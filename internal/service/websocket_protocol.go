@@ -0,0 +1,107 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// clientFrame is a frame sent by clients. Frames are validated against the rules below before
+// they are handled; event decides which fields are required.
+type clientFrame struct {
+	V            int    `json:"v"            v:"required#Protocol version is required"`
+	Event        int    `json:"event"        v:"required|in:1,8,12,13,17,18,19#Event is required|Unknown event"`
+	RequestId    string `json:"requestId"    v:"max-length:64#Request ID must be at most 64 characters"`
+	RoomId       uint   `json:"roomId"       v:"required-if:event,18,event,19#Room ID is required"`
+	Id           uint   `json:"id"           v:"required-if:event,8,event,12,event,13#Message ID is required"`
+	Seq          uint   `json:"seq"`
+	Type         int    `json:"type"         v:"in:0,1,2#Invalid message type"`
+	ParentId     uint   `json:"parentId"`
+	AttachmentId uint   `json:"attachmentId" v:"required-if-all:event,1,type,1|required-if-all:event,1,type,2#Attachment is required|Attachment is required"`
+	Content      string `json:"content"      v:"required-if-all:event,1,type,0|required-if:event,12,event,13,event,17|max-length:5000#Content is required|Content is required|Content must be at most 5000 characters"`
+	Nonce        string `json:"nonce"        v:"max-length:64#Nonce must be at most 64 characters"`
+}
+
+// MarshalJSON encodes a frame with the current protocol version
+func (msg WebSocketMessage) MarshalJSON() ([]byte, error) {
+	type frame WebSocketMessage
+	f := frame(msg)
+	f.V = consts.WsProtocolVersion
+	return json.Marshal(f)
+}
+
+// decodeFrame decodes and validates a frame sent by the client. The frame is returned even if
+// it is invalid, so its request ID can be echoed in the error.
+func decodeFrame(ctx context.Context, data []byte) (*clientFrame, error) {
+	frame := &clientFrame{}
+	if err := json.Unmarshal(data, frame); err != nil {
+		return frame, gerror.New("Invalid frame")
+	}
+	if frame.V != consts.WsProtocolVersion {
+		return frame, gerror.Newf("Unsupported protocol version %d, expected %d", frame.V, consts.WsProtocolVersion)
+	}
+	if err := g.Validator().Data(frame).Run(ctx); err != nil {
+		return frame, err.FirstError()
+	}
+	return frame, nil
+}
+
+// handleFrame handles a frame sent by the client. Requests carrying a request ID are
+// acknowledged with a WsMsgTypeAck frame; failed ones are answered with a WsMsgTypeError
+// frame carrying the request ID.
+func (c *Connection) handleFrame(data []byte) {
+	ctx := context.Background()
+	frame, err := decodeFrame(ctx, data)
+	if err == nil {
+		var ack *WebSocketMessage
+		if ack, err = c.processFrame(ctx, frame); err == nil {
+			if frame.RequestId != "" {
+				ack.Event = consts.WsMsgTypeAck
+				ack.RequestId = frame.RequestId
+				ack.Timestamp = time.Now().Format(time.RFC3339)
+				c.sendMessage(*ack)
+			}
+			return
+		}
+	}
+	c.sendMessage(WebSocketMessage{
+		Event:     consts.WsMsgTypeError,
+		RoomId:    frame.RoomId,
+		RequestId: frame.RequestId,
+		Content:   err.Error(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// processFrame carries out a validated frame and returns the content of its acknowledgement
+func (c *Connection) processFrame(ctx context.Context, frame *clientFrame) (*WebSocketMessage, error) {
+	// Subscription changes are also confirmed with a frame of their own
+	switch frame.Event {
+	case consts.WsMsgTypeSubscribe:
+		return &WebSocketMessage{RoomId: frame.RoomId}, c.subscribe(frame.RoomId, frame.Seq)
+	case consts.WsMsgTypeUnsubscribe:
+		if !c.unsubscribe(frame.RoomId, "") {
+			return nil, gerror.New(consts.ErrNotSubscribed)
+		}
+		return &WebSocketMessage{RoomId: frame.RoomId}, nil
+	}
+
+	// Other frames belong to a subscribed room
+	if frame.RoomId == 0 {
+		frame.RoomId = c.defaultRoom
+	}
+	sub := c.subscription(frame.RoomId)
+	if sub == nil {
+		return nil, gerror.New(consts.ErrNotSubscribed)
+	}
+
+	if frame.Event == consts.WsMsgTypeMessage {
+		return c.sendChatMessage(ctx, sub, frame)
+	}
+	// Read markers, reactions and typing indicators are handled instead of being stored
+	return &WebSocketMessage{RoomId: sub.roomId, Id: frame.Id}, c.handleAction(ctx, sub, frame)
+}
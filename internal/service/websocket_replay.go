@@ -0,0 +1,134 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// replayBatchSize is the number of missed messages loaded from the database at a time
+const replayBatchSize = 100
+
+// heldFrame is a live frame of a room held back while the missed messages are replayed
+type heldFrame struct {
+	seq      uint // Sequence number of the message in the frame, 0 for other frames
+	msgBytes []byte
+}
+
+// deliver queues a frame of a subscribed room for the connection. While the missed messages of
+// the room are replayed, live frames are held back and sent after them. It reports false if the
+// send buffer is full.
+func (c *Connection) deliver(roomId, seq uint, msgBytes []byte) bool {
+	if sub := c.subscription(roomId); sub != nil {
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		if sub.replaying {
+			sub.held = append(sub.held, heldFrame{seq: seq, msgBytes: msgBytes})
+			return true
+		}
+	}
+	select {
+	case c.send <- msgBytes:
+		return true
+	default:
+		return false
+	}
+}
+
+// replay sends the messages of a room stored after the message with sequence number afterSeq,
+// then the live frames held back meanwhile, skipping the messages that were already replayed,
+// and switches the subscription to live delivery. Thread replies have sequence numbers in their
+// room, so they are replayed as WsMsgTypeThreadReply frames with their parentId to leave no gaps.
+func (c *Connection) replay(sub *subscription, afterSeq uint) error {
+	ctx := context.Background()
+	messageService := NewMessageService()
+	lastSeq := afterSeq
+	for {
+		messages, err := messageService.GetMissedMessages(ctx, c.user.Id, sub.roomId, lastSeq, replayBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			frame := WebSocketMessage{
+				Event:      consts.WsMsgTypeMessage,
+				Type:       msg.Type,
+				RoomId:     msg.RoomId,
+				Id:         msg.Id,
+				Seq:        msg.Seq,
+				ParentId:   msg.ParentId,
+				Attachment: msg.Attachment,
+				Content:    msg.Content,
+				Timestamp:  replayTimestamp(msg.Timestamp),
+				UserId:     msg.UserId,
+				Username:   msg.Username,
+				Nickname:   msg.Nickname,
+				Avatar:     msg.Avatar,
+			}
+			// Replies look the same as when they are delivered live
+			if msg.ParentId > 0 {
+				frame.Event = consts.WsMsgTypeThreadReply
+				frame.Data = g.Map{
+					"roomId":      msg.RoomId,
+					"messageType": msg.Type,
+				}
+			}
+			msgBytes, _ := json.Marshal(frame)
+			if !c.sendWait(msgBytes) {
+				return gerror.New("Too many missed messages to replay")
+			}
+			lastSeq = msg.Seq
+		}
+		if len(messages) < replayBatchSize {
+			break
+		}
+	}
+
+	// Frames held back while sending the held frames are sent in the next round
+	for {
+		sub.mu.Lock()
+		held := sub.held
+		sub.held = nil
+		if len(held) == 0 {
+			sub.replaying = false
+			sub.mu.Unlock()
+			return nil
+		}
+		sub.mu.Unlock()
+
+		for _, frame := range held {
+			if frame.seq > 0 && frame.seq <= lastSeq {
+				continue
+			}
+			if !c.sendWait(frame.msgBytes) {
+				return gerror.New("Too many missed messages to replay")
+			}
+		}
+	}
+}
+
+// replayTimestamp converts the stored time of a message to RFC3339, the format of live frames
+func replayTimestamp(timestamp string) string {
+	t, err := gtime.StrToTime(timestamp)
+	if err != nil {
+		return timestamp
+	}
+	return t.Time.Format(time.RFC3339)
+}
+
+// sendWait queues a frame for the connection, waiting up to closeWriteWait for room in the send
+// buffer. It reports false if the frame could not be queued.
+func (c *Connection) sendWait(msgBytes []byte) bool {
+	select {
+	case c.send <- msgBytes:
+		return true
+	case <-c.quit:
+		return false
+	case <-time.After(closeWriteWait):
+		return false
+	}
+}
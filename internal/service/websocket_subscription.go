@@ -0,0 +1,222 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// maxSubscriptions is the maximum number of rooms a connection may subscribe to
+const maxSubscriptions = 100
+
+// subscription is a room a connection receives the frames of
+type subscription struct {
+	roomId    uint
+	direct    bool        // Whether the room is a direct conversation
+	typing    typingState // Typing indicator of the user in the room on this connection
+	mu        sync.Mutex  // Guards replaying and held
+	replaying bool        // Whether missed messages are being replayed
+	held      []heldFrame // Live frames held back until the replay finished
+}
+
+// subscribe subscribes the connection to a room after checking that the user may read it.
+// The subscription is confirmed with a WsMsgTypeSubscribe frame; subscribing again only
+// repeats the confirmation. If lastSeq is set, the messages stored after the message with
+// that sequence number are replayed before any live frame of the room.
+func (c *Connection) subscribe(roomId, lastSeq uint) error {
+	ctx := context.Background()
+	if err := c.manager.permService.Check(ctx, c.user.Id, roomId, consts.PermMessageRead); err != nil {
+		return err
+	}
+	room, err := dao.NewChatRoomDao().GetByID(ctx, roomId)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return gerror.New("Chat room not found")
+	}
+
+	sub := &subscription{
+		roomId:    roomId,
+		direct:    room.Type == consts.RoomTypeDirect,
+		replaying: lastSeq > 0,
+	}
+	added, firstInRoom, err := c.manager.addSubscription(c, sub)
+	if err != nil {
+		return err
+	}
+	c.sendMessage(WebSocketMessage{
+		Event:     consts.WsMsgTypeSubscribe,
+		RoomId:    roomId,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	if !added {
+		return nil
+	}
+
+	// Send current user list to all users in the room
+	c.manager.broadcastUserList(roomId)
+
+	// Notify other users that new user joined, unless they are already connected from another device
+	if firstInRoom {
+		c.manager.broadcastToRoom(roomId, WebSocketMessage{
+			Event:     consts.WsMsgTypeJoin,
+			Content:   fmt.Sprintf("%s joined the room", c.user.Nickname),
+			Timestamp: time.Now().Format(time.RFC3339),
+			UserId:    c.user.Id,
+			Username:  c.user.Username,
+			Nickname:  c.user.Nickname,
+			Avatar:    c.user.Avatar,
+		})
+	}
+
+	// Messages missed while disconnected go before the frames above
+	if sub.replaying {
+		if err := c.replay(sub, lastSeq); err != nil {
+			c.closeWithError(err.Error())
+		}
+	}
+	return nil
+}
+
+// unsubscribe unsubscribes the connection from a room and confirms it with a WsMsgTypeUnsubscribe
+// frame carrying the reason, empty if the client asked for it. It reports whether the connection
+// was subscribed.
+func (c *Connection) unsubscribe(roomId uint, reason string) bool {
+	sub := c.manager.removeSubscription(c, roomId)
+	if sub == nil {
+		return false
+	}
+	c.sendMessage(WebSocketMessage{
+		Event:     consts.WsMsgTypeUnsubscribe,
+		RoomId:    roomId,
+		Content:   reason,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+
+	c.stopTyping(sub)
+	c.manager.broadcastUserList(roomId)
+	return true
+}
+
+// subscription returns the subscription of the connection to a room, nil if it is not subscribed
+func (c *Connection) subscription(roomId uint) *subscription {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+	return c.rooms[roomId]
+}
+
+// addSubscription adds a subscription to a connection and the room's connections. It reports
+// whether the subscription is new and whether it is the user's first connection to the room.
+func (m *WebSocketManager) addSubscription(conn *Connection, sub *subscription) (added, firstInRoom bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The connection may have closed while checking the room
+	if _, ok := m.sockets.Load(conn.id); !ok {
+		return false, false, gerror.New("Connection is closed")
+	}
+
+	conn.roomsMu.Lock()
+	if _, ok := conn.rooms[sub.roomId]; ok {
+		conn.roomsMu.Unlock()
+		return false, false, nil
+	}
+	if len(conn.rooms) >= maxSubscriptions {
+		conn.roomsMu.Unlock()
+		return false, false, gerror.Newf("A connection may subscribe to at most %d chat rooms", maxSubscriptions)
+	}
+	conn.rooms[sub.roomId] = sub
+	conn.roomsMu.Unlock()
+
+	// Get or create room connections map
+	value, _ := m.connections.LoadOrStore(sub.roomId, &sync.Map{})
+	roomConns := value.(*sync.Map)
+	firstInRoom = !hasUserConnection(roomConns, conn.user.Id)
+	roomConns.Store(conn.id, conn)
+	return true, firstInRoom, nil
+}
+
+// removeSubscription removes the subscription of a connection to a room, returning it,
+// nil if the connection was not subscribed
+func (m *WebSocketManager) removeSubscription(conn *Connection, roomId uint) *subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn.roomsMu.Lock()
+	sub := conn.rooms[roomId]
+	delete(conn.rooms, roomId)
+	conn.roomsMu.Unlock()
+	if sub != nil {
+		m.removeFromRoom(conn, roomId)
+	}
+	return sub
+}
+
+// removeFromRoom removes a connection from the connections of a room, dropping the room once
+// it has none. The caller must hold m.mu.
+func (m *WebSocketManager) removeFromRoom(conn *Connection, roomId uint) {
+	value, ok := m.connections.Load(roomId)
+	if !ok {
+		return
+	}
+	roomConns := value.(*sync.Map)
+	roomConns.Delete(conn.id)
+
+	// If room is empty, remove it from connections map
+	empty := true
+	roomConns.Range(func(key, value interface{}) bool {
+		empty = false
+		return false
+	})
+	if empty {
+		m.connections.Delete(roomId)
+	}
+}
+
+// UnsubscribeRoomUser unsubscribes a user's live connections from a room, e.g. after leaving it
+func (m *WebSocketManager) UnsubscribeRoomUser(roomId, userId uint, reason string) {
+	m.unsubscribeRoom(roomId, reason, func(conn *Connection) bool {
+		return conn.user.Id == userId
+	})
+}
+
+// UnsubscribeRoom unsubscribes all live connections from a room, e.g. after it was deleted
+func (m *WebSocketManager) UnsubscribeRoom(roomId uint, reason string) {
+	m.unsubscribeRoom(roomId, reason, func(conn *Connection) bool {
+		return true
+	})
+}
+
+// unsubscribeRoom unsubscribes the connections to a room matching the filter
+func (m *WebSocketManager) unsubscribeRoom(roomId uint, reason string, match func(conn *Connection) bool) {
+	value, ok := m.connections.Load(roomId)
+	if !ok {
+		return
+	}
+	var conns []*Connection
+	value.(*sync.Map).Range(func(connId, connValue interface{}) bool {
+		if conn := connValue.(*Connection); match(conn) {
+			conns = append(conns, conn)
+		}
+		return true
+	})
+	for _, conn := range conns {
+		conn.unsubscribe(roomId, reason)
+	}
+}
+
+// sendMessage queues a frame for the connection only, dropping it if the send buffer is full
+func (c *Connection) sendMessage(msg WebSocketMessage) {
+	msgBytes, _ := json.Marshal(msg)
+	select {
+	case c.send <- msgBytes:
+	default:
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"chatroom/internal/dao"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// deliverReply sends a thread reply as a WsMsgTypeThreadReply frame to the connections of
+// the thread participants still in the room and broadcasts the updated reply count of the root to the room,
+// keeping replies out of the main room timeline
+func (m *WebSocketManager) deliverReply(ctx context.Context, roomId uint, msg WebSocketMessage) {
+	messageDao := dao.NewMessageDao()
+	participants, err := messageDao.ListThreadParticipants(ctx, msg.ParentId)
+	if err != nil {
+		g.Log().Error(ctx, "List thread participants failed:", err)
+		return
+	}
+
+	reply := msg
+	reply.Event = consts.WsMsgTypeThreadReply
+	reply.RoomId = roomId
+	reply.Data = g.Map{
+		"roomId":      roomId,
+		"messageType": msg.Type,
+	}
+	msgBytes, _ := json.Marshal(reply)
+	for _, userId := range participants {
+		m.sendToUser(userId, msgBytes)
+	}
+
+	replyCount, lastReplyAt, err := messageDao.GetThreadStats(ctx, msg.ParentId)
+	if err != nil {
+		g.Log().Error(ctx, "Get thread stats failed:", err)
+		return
+	}
+	m.broadcastToRoom(roomId, WebSocketMessage{
+		Event:     consts.WsMsgTypeThreadUpdate,
+		Id:        msg.ParentId,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Data: g.Map{
+			"roomId":      roomId,
+			"replyCount":  replyCount,
+			"lastReplyAt": lastReplyAt,
+		},
+	})
+}
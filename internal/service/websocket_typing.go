@@ -0,0 +1,137 @@
+package service
+
+import (
+	"chatroom/internal/consts"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+const (
+	// typingThrottle is the minimum interval between the typing start frames forwarded for a connection
+	typingThrottle = 3 * time.Second
+	// typingTimeout is how long a typing indicator lasts without a new start frame
+	typingTimeout = 6 * time.Second
+)
+
+// typingState tracks whether a connection's user is typing in a subscribed room. Clients repeat
+// start frames while the user keeps typing; the indicator expires when they stop arriving.
+type typingState struct {
+	mu          sync.Mutex
+	timer       *time.Timer // Expires the indicator, nil while not typing
+	lastStart   time.Time   // Last start frame received
+	lastForward time.Time   // Last start frame forwarded to the room
+}
+
+// handleTyping handles a typing start or stop frame. Typing indicators are only forwarded to the
+// other connections of the room and never stored.
+func (c *Connection) handleTyping(sub *subscription, state string) error {
+	switch state {
+	case consts.TypingStart:
+		c.startTyping(sub)
+		return nil
+	case consts.TypingStop:
+		c.stopTyping(sub)
+		return nil
+	}
+	return gerror.New("Invalid typing state")
+}
+
+// startTyping marks the user as typing, forwarding start frames at most once per typingThrottle
+func (c *Connection) startTyping(sub *subscription) {
+	typing := &sub.typing
+	typing.mu.Lock()
+	now := time.Now()
+	typing.lastStart = now
+	if typing.timer == nil {
+		// Members who may not send, e.g. muted ones, are not shown as typing
+		if !c.canSend(sub.roomId) {
+			typing.mu.Unlock()
+			return
+		}
+		typing.timer = time.AfterFunc(typingTimeout, func() { c.expireTyping(sub) })
+	} else if now.Sub(typing.lastForward) < typingThrottle {
+		typing.mu.Unlock()
+		return
+	}
+	typing.lastForward = now
+	typing.mu.Unlock()
+
+	// Broadcast outside the lock, as a full send buffer removes connections, stopping their typing
+	c.manager.broadcastTyping(c, sub.roomId, consts.TypingStart)
+}
+
+// stopTyping clears the typing indicator of the user, e.g. after sending a message or disconnecting
+func (c *Connection) stopTyping(sub *subscription) {
+	typing := &sub.typing
+	typing.mu.Lock()
+	if typing.timer == nil {
+		typing.mu.Unlock()
+		return
+	}
+	typing.timer.Stop()
+	typing.timer = nil
+	typing.mu.Unlock()
+
+	c.manager.broadcastTyping(c, sub.roomId, consts.TypingStop)
+}
+
+// expireTyping stops the typing indicator once no start frame arrived for typingTimeout
+func (c *Connection) expireTyping(sub *subscription) {
+	typing := &sub.typing
+	typing.mu.Lock()
+	if typing.timer == nil {
+		typing.mu.Unlock()
+		return
+	}
+	// A start frame may have arrived while the timer fired
+	if remaining := typingTimeout - time.Since(typing.lastStart); remaining > 0 {
+		typing.timer.Reset(remaining)
+		typing.mu.Unlock()
+		return
+	}
+	typing.mu.Unlock()
+	c.stopTyping(sub)
+}
+
+// canSend reports whether the connection's user may currently send messages to a room
+func (c *Connection) canSend(roomId uint) bool {
+	ctx := context.Background()
+	messageService := NewMessageService()
+	if err := messageService.permService.Check(ctx, c.user.Id, roomId, consts.PermMessageSend); err != nil {
+		return false
+	}
+	return messageService.checkMute(ctx, c.user.Id, roomId) == nil
+}
+
+// broadcastTyping sends a typing frame of a connection's user to the other users in a room
+func (m *WebSocketManager) broadcastTyping(sender *Connection, roomId uint, state string) {
+	value, ok := m.connections.Load(roomId)
+	if !ok {
+		return
+	}
+	msgBytes, _ := json.Marshal(WebSocketMessage{
+		Event:     consts.WsMsgTypeTyping,
+		RoomId:    roomId,
+		Content:   state,
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserId:    sender.user.Id,
+		Username:  sender.user.Username,
+		Nickname:  sender.user.Nickname,
+		Avatar:    sender.user.Avatar,
+	})
+
+	value.(*sync.Map).Range(func(key, value interface{}) bool {
+		conn := value.(*Connection)
+		if conn.user.Id == sender.user.Id {
+			return true
+		}
+		if !conn.deliver(roomId, 0, msgBytes) {
+			m.removeConnection(conn)
+		}
+		return true
+	})
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// LocalStorage stores objects as files below a directory on the local disk
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a LocalStorage rooted at a directory, creating it if needed
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Put writes an object to a temporary file first, so readers never see partial objects
+func (s *LocalStorage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return gerror.Newf("Stored %d bytes instead of %d", written, size)
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get opens the file of an object
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file of an object
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file below the root, rejecting keys that would escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", gerror.Newf("Invalid storage key: %s", key)
+	}
+	return path, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newLocalStorage(t *testing.T) (*LocalStorage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewLocalStorage(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	store, dir := newLocalStorage(t)
+	ctx := context.Background()
+	if err := store.Put(ctx, "2024/01/02/abc", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "2024", "01", "02", "abc")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := store.Get(ctx, "2024/01/02/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "hello" {
+		t.Errorf("got %q", data)
+	}
+
+	if err := store.Delete(ctx, "2024/01/02/abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "2024/01/02/abc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "2024/01/02/abc"); err != nil {
+		t.Errorf("deleting a missing object failed: %v", err)
+	}
+}
+
+func TestLocalSizeMismatch(t *testing.T) {
+	store, _ := newLocalStorage(t)
+	ctx := context.Background()
+	if err := store.Put(ctx, "short", strings.NewReader("abc"), 5, "text/plain"); err == nil {
+		t.Fatal("Put succeeded with fewer bytes than the size")
+	}
+	if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("partial object was stored: %v", err)
+	}
+}
+
+func TestLocalPathTraversal(t *testing.T) {
+	store, dir := newLocalStorage(t)
+	ctx := context.Background()
+	for _, key := range []string{"../escaped", "a/../../escaped", "..", ""} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want invalid key", key, err)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the root: %v", err)
+	}
+
+	// Keys may contain dot segments as long as they stay below the root
+	if err := store.Put(ctx, "a/../inside", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Errorf("Put of a key inside the root failed: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// s3UnsignedPayload is sent as the payload hash so uploads can be streamed without hashing them first
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config configures an S3-compatible object storage, e.g. AWS S3 or MinIO
+type S3Config struct {
+	Endpoint  string `json:"endpoint"`  // Service URL, e.g. https://s3.us-east-1.amazonaws.com or http://127.0.0.1:9000
+	Region    string `json:"region"`    // Signing region
+	Bucket    string `json:"bucket"`    // Bucket storing the objects
+	AccessKey string `json:"accessKey"` // Access key ID
+	SecretKey string `json:"secretKey"` // Secret access key
+	PathStyle bool   `json:"pathStyle"` // Address the bucket in the path instead of the host name, as MinIO expects
+}
+
+// S3Storage stores objects in a bucket of an S3-compatible service, signing requests with AWS Signature Version 4
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Storage creates an S3Storage from its configuration
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, gerror.New("S3 storage requires an endpoint and a bucket")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// Put uploads an object with a single PUT request
+func (s *S3Storage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads an object
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete deletes an object
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// newRequest creates a request for an object of the bucket
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	objectURL := *s.endpoint
+	if s.config.PathStyle {
+		objectURL.Path += "/" + s.config.Bucket + "/" + key
+	} else {
+		objectURL.Host = s.config.Bucket + "." + objectURL.Host
+		objectURL.Path += "/" + key
+	}
+	return http.NewRequestWithContext(ctx, method, objectURL.String(), body)
+}
+
+// do signs and sends a request, turning error responses into errors
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	s.sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, gerror.Newf("S3 %s %s failed with status %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering the host and every header
+// set on the request. The X-Amz-Content-Sha256 header must already be set.
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// s3EscapePath URI-encodes every byte of a path except unreserved characters and slashes
+func s3EscapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
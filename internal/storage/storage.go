@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// Storage drivers selectable with storage.driver
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores the contents of attachments under keys made of slash-separated
+// letters, digits, dashes, underscores and dots
+type Storage interface {
+	// Put stores size bytes read from content under a key, replacing any existing object
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Get opens the object stored under a key; the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under a key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultStorage Storage
+	defaultErr     error
+	defaultOnce    sync.Once
+)
+
+// Default returns the storage backend configured under storage
+func Default(ctx context.Context) (Storage, error) {
+	defaultOnce.Do(func() {
+		defaultStorage, defaultErr = New(ctx)
+	})
+	return defaultStorage, defaultErr
+}
+
+// New creates the storage backend selected by storage.driver
+func New(ctx context.Context) (Storage, error) {
+	switch driver := g.Cfg().MustGet(ctx, "storage.driver", DriverLocal).String(); driver {
+	case DriverLocal:
+		return NewLocalStorage(g.Cfg().MustGet(ctx, "storage.local.path", "uploads").String())
+	case DriverS3:
+		var config S3Config
+		if err := g.Cfg().MustGet(ctx, "storage.s3").Scan(&config); err != nil {
+			return nil, err
+		}
+		return NewS3Storage(config)
+	default:
+		return nil, gerror.Newf("Unknown storage driver: %s", driver)
+	}
+}
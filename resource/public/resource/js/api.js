@@ -1,11 +1,53 @@
-import { Errors } from './constants.js';
+import { ErrorCode, Errors } from './constants.js';
 
 class Api {
+    static refreshing = null;
+
     static getToken() {
         return localStorage.getItem('token');
     }
 
-    static async request(url, options = {}) {
+    static getRefreshToken() {
+        return localStorage.getItem('refreshToken');
+    }
+
+    static clearSession() {
+        localStorage.removeItem('token');
+        localStorage.removeItem('refreshToken');
+        localStorage.removeItem('user');
+    }
+
+    // 使用刷新令牌换取新的访问令牌，并发调用时只发起一次请求
+    static async refreshToken() {
+        const refreshToken = this.getRefreshToken();
+        if (!refreshToken) {
+            return false;
+        }
+
+        if (!this.refreshing) {
+            this.refreshing = fetch('/api/user/refresh', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ refreshToken })
+            })
+                .then(response => response.json())
+                .then(data => {
+                    if (data.code !== 0) {
+                        return false;
+                    }
+                    localStorage.setItem('token', data.data.token);
+                    localStorage.setItem('refreshToken', data.data.refreshToken);
+                    return true;
+                })
+                .catch(() => false)
+                .finally(() => {
+                    this.refreshing = null;
+                });
+        }
+        return this.refreshing;
+    }
+
+    static async request(url, options = {}, retry = true) {
         const token = this.getToken();
         const defaultOptions = {
             headers: {
@@ -22,15 +64,21 @@ class Api {
                 ...options.headers
             }
         };
+        // 上传文件时由浏览器设置multipart边界
+        if (options.body instanceof FormData) {
+            delete finalOptions.headers['Content-Type'];
+        }
 
         try {
             const response = await fetch(url, finalOptions);
             const data = await response.json();
 
-            if (data.code === 401) {
-                // Token过期或无效，跳转到登录页
-                localStorage.removeItem('token');
-                localStorage.removeItem('user');
+            if (data.code === 401 || data.code === ErrorCode.NOT_AUTHORIZED) {
+                // Token过期时先尝试刷新，失败再跳转到登录页
+                if (retry && await this.refreshToken()) {
+                    return this.request(url, options, false);
+                }
+                this.clearSession();
                 window.location.href = '/html/login.html';
                 return null;
             }
@@ -50,6 +98,13 @@ class Api {
         }
     }
 
+    // 用户相关接口
+    static async logout() {
+        return this.request('/api/user/logout', {
+            method: 'POST'
+        });
+    }
+
     // 聊天室相关接口
     static async getRoomList(page = 1, size = 50) {
         return this.request(`/api/chatroom/list?page=${page}&size=${size}`);
@@ -81,8 +136,194 @@ class Api {
     }
 
     // 聊天相关接口
-    static async getChatHistory(roomId, page = 1, size = 50) {
-        return this.request(`/api/chat/history/${roomId}?page=${page}&size=${size}`);
+    // cursor 可为 { before }、{ after } 或 { around }，值为消息ID
+    static async getChatHistory(roomId, cursor = {}, size = 50) {
+        const params = new URLSearchParams({ ...cursor, size });
+        return this.request(`/api/chat/history/${roomId}?${params}`);
+    }
+
+    static async markRead(roomId, messageId) {
+        return this.request(`/api/chat/read/${roomId}`, {
+            method: 'POST',
+            body: JSON.stringify({ messageId })
+        });
+    }
+
+    static async getUnread() {
+        return this.request('/api/chat/unread');
+    }
+
+    static async editMessage(messageId, content) {
+        return this.request(`/api/chat/message/edit/${messageId}`, {
+            method: 'POST',
+            body: JSON.stringify({ content })
+        });
+    }
+
+    // filters 可包含 roomId、userId、from、to（YYYY-MM-DD）
+    static async searchMessages(query, filters = {}, page = 1, size = 20) {
+        const params = new URLSearchParams({ query, ...filters, page, size });
+        return this.request(`/api/chat/search?${params}`);
+    }
+
+    static async getNotifications(unread = false, page = 1, size = 20) {
+        return this.request(`/api/chat/notification/list?unread=${unread}&page=${page}&size=${size}`);
+    }
+
+    static async dismissNotification(notificationId) {
+        return this.request(`/api/chat/notification/dismiss/${notificationId}`, {
+            method: 'POST'
+        });
+    }
+
+    static async getThread(messageId, page = 1, size = 50) {
+        return this.request(`/api/chat/thread/${messageId}?page=${page}&size=${size}`);
+    }
+
+    static async deleteMessage(messageId) {
+        return this.request(`/api/chat/message/delete/${messageId}`, {
+            method: 'POST'
+        });
+    }
+
+    // 附件相关接口
+    static async uploadAttachment(roomId, file) {
+        const formData = new FormData();
+        formData.append('file', file);
+        return this.request(`/api/chat/attachment/upload/${roomId}`, {
+            method: 'POST',
+            body: formData
+        });
+    }
+
+    // 断点续传上传（tus协议），每个分块小于服务端请求体上限；上传完成后由服务端发送文件消息
+    static uploadChunkSize = 5 * 1024 * 1024;
+
+    static async uploadResumable(roomId, file, onProgress) {
+        // 记录上传地址，刷新页面后重新选择同一文件可继续上传
+        const key = `upload:${roomId}:${file.name}:${file.size}:${file.lastModified}`;
+        let url = localStorage.getItem(key);
+        let offset = url ? await this.getUploadOffset(url) : null;
+        if (offset === null) {
+            const metadata = `roomId ${btoa(String(roomId))},filename ${btoa(unescape(encodeURIComponent(file.name)))}`;
+            const response = await this.tusRequest('/api/upload', {
+                method: 'POST',
+                headers: { 'Upload-Length': String(file.size), 'Upload-Metadata': metadata }
+            });
+            url = response.headers.get('Location');
+            offset = 0;
+            localStorage.setItem(key, url);
+        }
+
+        let retries = 0;
+        while (offset < file.size) {
+            if (onProgress) onProgress(offset, file.size);
+            try {
+                const response = await this.tusRequest(url, {
+                    method: 'PATCH',
+                    headers: {
+                        'Upload-Offset': String(offset),
+                        'Content-Type': 'application/offset+octet-stream'
+                    },
+                    body: file.slice(offset, offset + this.uploadChunkSize)
+                });
+                offset = parseInt(response.headers.get('Upload-Offset'), 10);
+                retries = 0;
+            } catch (error) {
+                // 网络中断或偏移量冲突时向服务端查询已接收的字节数后重试
+                if ((error.status !== undefined && error.status !== 409) || ++retries > 3) {
+                    if (error.status === 404 || error.status === 410) localStorage.removeItem(key);
+                    throw error;
+                }
+                await new Promise(resolve => setTimeout(resolve, 1000 * retries));
+                offset = await this.getUploadOffset(url);
+                if (offset === null) {
+                    localStorage.removeItem(key);
+                    throw error;
+                }
+            }
+        }
+        localStorage.removeItem(key);
+        if (onProgress) onProgress(file.size, file.size);
+    }
+
+    // 查询上传已接收的字节数，上传不存在或已过期时返回null
+    static async getUploadOffset(url) {
+        try {
+            const response = await this.tusRequest(url, { method: 'HEAD' });
+            return parseInt(response.headers.get('Upload-Offset'), 10);
+        } catch (error) {
+            if (error.status === 404 || error.status === 410) return null;
+            throw error;
+        }
+    }
+
+    // 发送tus请求；协议错误返回纯文本，认证失败返回JSON
+    static async tusRequest(url, options, retry = true) {
+        const token = this.getToken();
+        const response = await fetch(url, {
+            ...options,
+            headers: {
+                ...options.headers,
+                'Authorization': token ? `Bearer ${token}` : '',
+                'Tus-Resumable': '1.0.0'
+            }
+        });
+        if ((response.headers.get('Content-Type') || '').startsWith('application/json')) {
+            const data = await response.json();
+            if ((data.code === 401 || data.code === ErrorCode.NOT_AUTHORIZED) && retry && await this.refreshToken()) {
+                return this.tusRequest(url, options, false);
+            }
+            throw new Error(data.message || '上传失败');
+        }
+        if (!response.ok) {
+            const error = new Error(options.method === 'HEAD' ? '上传失败' : (await response.text() || '上传失败'));
+            error.status = response.status;
+            throw error;
+        }
+        return response;
+    }
+
+    // 下载附件或缩略图内容，出错时服务端返回JSON
+    static async getAttachment(url, retry = true) {
+        const token = this.getToken();
+        const response = await fetch(url, {
+            headers: { 'Authorization': token ? `Bearer ${token}` : '' }
+        });
+        if (!(response.headers.get('Content-Type') || '').startsWith('application/json')) {
+            return response.blob();
+        }
+
+        const data = await response.json();
+        if ((data.code === 401 || data.code === ErrorCode.NOT_AUTHORIZED) && retry && await this.refreshToken()) {
+            return this.getAttachment(url, false);
+        }
+        throw new Error(data.message || '下载失败');
+    }
+
+    // 存储用量相关接口
+    static async getStorageUsage() {
+        return this.request('/api/chat/storage/usage');
+    }
+
+    static async getRoomStorageUsage(roomId) {
+        return this.request(`/api/chat/storage/room/${roomId}`);
+    }
+
+    static async getStorageReport(limit = 10) {
+        return this.request(`/api/chat/storage/report?limit=${limit}`);
+    }
+
+    // 私聊相关接口
+    static async sendDirectMessage(userId, content, type = 0) {
+        return this.request(`/api/chat/direct/send/${userId}`, {
+            method: 'POST',
+            body: JSON.stringify({ content, type })
+        });
+    }
+
+    static async getDirectList() {
+        return this.request('/api/chat/direct/list');
     }
 }
 
@@ -1,9 +1,13 @@
-import { MessageType, WsMessageType } from './constants.js';
+import { MessageType, TypingState, WsMessageType } from './constants.js';
 
 class Chat {
     constructor() {
         this.currentUser = null;
         this.currentRoom = null;
+        this.prevCursor = 0;
+        this.loadingHistory = false;
+        this.typingSentAt = 0;
+        this.typingStopTimer = null;
         this.ws = new ChatWebSocket();
         this.ui = new ChatUI();
         this.setupEventListeners();
@@ -26,6 +30,9 @@ class Chat {
         // 加载聊天室列表
         await this.loadRoomList();
 
+        // 建立WebSocket连接，进入聊天室时再订阅
+        this.ws.connect();
+
         // 绑定消息输入事件
         const messageInput = document.getElementById('messageInput');
         messageInput.addEventListener('keypress', (e) => {
@@ -34,6 +41,7 @@ class Chat {
                 this.sendMessage();
             }
         });
+        messageInput.addEventListener('input', () => this.handleTyping());
     }
 
     setupEventListeners() {
@@ -41,6 +49,13 @@ class Chat {
         document.getElementById('imageInput').addEventListener('change', (e) => this.handleImageUpload(e));
         document.getElementById('fileInput').addEventListener('change', (e) => this.handleFileUpload(e));
 
+        // 滚动到顶部时加载更早的历史消息
+        this.ui.messageList.addEventListener('scroll', () => {
+            if (this.ui.messageList.scrollTop === 0) {
+                this.loadOlderMessages();
+            }
+        });
+
         // WebSocket错误处理
         window.addEventListener('ws:error', (e) => {
             const errorMessage = document.createElement('div');
@@ -63,14 +78,107 @@ class Chat {
 
     setupWebSocketHandlers() {
         // 处理不同类型的消息
-        this.ws.on(MessageType.TEXT, (message) => this.ui.appendMessage(message));
-        this.ws.on(MessageType.IMAGE, (message) => this.ui.appendMessage(message));
-        this.ws.on(MessageType.FILE, (message) => this.ui.appendMessage(message));
-        this.ws.on(MessageType.SYSTEM, (message) => this.ui.appendMessage(message));
-        this.ws.on(WsMessageType.USER_LIST, (message) => {
+        this.ws.on(WsMessageType.MESSAGE, (message) => {
+            if (message.type !== MessageType.SYSTEM) {
+                this.receiveMessage(message);
+            } else if (message.roomId === this.currentRoom) {
+                this.ui.appendMessage(message);
+            } else {
+                this.loadRoomList();
+            }
+        });
+        this.onRoom(WsMessageType.ERROR, (message) => {
+            // 请求的错误由发起请求处处理
+            if (message.requestId) return;
+            this.showError(message.content);
+        });
+        this.onRoom(WsMessageType.EDIT, (message) => this.ui.updateMessageContent(message.id, message.content));
+        this.onRoom(WsMessageType.DELETE, (message) => this.ui.markMessageDeleted(message.id));
+        this.onRoom(WsMessageType.REACTION, (message) => {
+            const div = this.ui.messageList.querySelector(`[data-message-id="${message.id}"] [data-emoji="${message.content}"]`);
+            // 自己的回应状态以本次变更为准，他人的变更保持原状态
+            const reacted = message.userId === this.currentUser?.id ? message.data.added : div?.dataset.reacted === '1';
+            this.ui.updateReaction(message.id, message.content, message.data.count, reacted);
+        });
+        this.ws.on(WsMessageType.NOTIFICATION, (message) => {
+            const notification = document.createElement('div');
+            notification.className = 'alert alert-warning';
+            notification.textContent = message.content;
+            // 点击提醒即视为已读
+            notification.onclick = () => {
+                Api.dismissNotification(message.data.id);
+                notification.remove();
+            };
+            this.ui.messageList.appendChild(notification);
+        });
+        this.onRoom(WsMessageType.THREAD_UPDATE, (message) => this.ui.updateThread(message.id, message.data.replyCount));
+        this.ws.on(WsMessageType.THREAD_REPLY, (message) => {
+            const threadReply = document.createElement('div');
+            threadReply.className = 'alert alert-light';
+            threadReply.textContent = `${message.nickname} 在话题中回复：${message.content}`;
+            this.ui.messageList.appendChild(threadReply);
+        });
+        this.ws.on(WsMessageType.DIRECT, (message) => {
+            const directMessage = document.createElement('div');
+            directMessage.className = 'alert alert-info';
+            directMessage.textContent = `来自 ${message.nickname} 的私信：${message.content}`;
+            this.ui.messageList.appendChild(directMessage);
+        });
+        this.onRoom(WsMessageType.TYPING, (message) => {
+            this.ui.updateTyping(message.userId, message.nickname, message.content === TypingState.START);
+        });
+        this.onRoom(WsMessageType.USER_LIST, (message) => {
             this.ui.updateUserList(message.data);
             this.loadRoomList(); // 刷新聊天室列表以更新在线人数
         });
+        this.onRoom(WsMessageType.UNSUBSCRIBE, (message) => {
+            // 仅处理服务端取消的订阅，如被移出聊天室或聊天室被删除
+            if (!message.content) return;
+            this.currentRoom = null;
+            this.ui.clearChatArea();
+            const notice = document.createElement('div');
+            notice.className = 'alert alert-warning';
+            notice.textContent = message.content;
+            this.ui.messageList.appendChild(notice);
+            this.loadRoomList();
+        });
+    }
+
+    showError(content) {
+        const errorMessage = document.createElement('div');
+        errorMessage.className = 'alert alert-warning';
+        errorMessage.textContent = content;
+        this.ui.messageList.appendChild(errorMessage);
+    }
+
+    // 注册只处理当前聊天室帧的处理器，其他聊天室的帧仅刷新聊天室列表
+    onRoom(messageType, handler) {
+        this.ws.on(messageType, (message) => {
+            if (message.roomId && message.roomId !== this.currentRoom) {
+                this.loadRoomList();
+                return;
+            }
+            handler(message);
+        });
+    }
+
+    toggleReaction(messageId, emoji, reacted) {
+        this.ws.react(messageId, emoji, reacted);
+    }
+
+    receiveMessage(message) {
+        // 其他聊天室的消息计入未读数
+        if (message.roomId !== this.currentRoom) {
+            this.loadRoomList();
+            return;
+        }
+        this.ui.appendMessage(message);
+        this.ui.updateTyping(message.userId, message.nickname, false);
+
+        // 当前聊天室中收到的他人消息直接标记为已读
+        if (message.id && message.userId !== this.currentUser?.id) {
+            this.ws.markRead(message.id);
+        }
     }
 
     async loadRoomList() {
@@ -91,14 +199,24 @@ class Chat {
             
             await Api.joinRoom(roomId);
             this.currentRoom = roomId;
-            this.ws.connect(roomId);
 
             // 加载历史消息
             const history = await Api.getChatHistory(roomId);
+            this.prevCursor = history.prevCursor;
             this.ui.clearChatArea();
             history.messages.reverse().forEach(msg => {
                 this.ui.appendMessage(msg);
             });
+
+            // 从最后一条历史消息之后开始接收，加载期间的新消息会先补发
+            const lastSeq = history.messages.reduce((seq, msg) => Math.max(seq, msg.seq), 0);
+            this.ws.subscribe(roomId, lastSeq);
+
+            // 标记历史消息为已读
+            if (history.messages.length > 0) {
+                const lastMessage = history.messages[history.messages.length - 1];
+                Api.markRead(roomId, lastMessage.id).catch(err => console.error('标记已读失败:', err));
+            }
             
             // 更新UI状态
             this.loadRoomList();
@@ -108,15 +226,38 @@ class Chat {
         }
     }
 
+    async loadOlderMessages() {
+        if (!this.currentRoom || !this.prevCursor || this.loadingHistory) return;
+
+        this.loadingHistory = true;
+        try {
+            const history = await Api.getChatHistory(this.currentRoom, { before: this.prevCursor });
+            // 保持当前可见的消息位置不变
+            const scrollHeight = this.ui.messageList.scrollHeight;
+            history.messages.forEach(msg => this.ui.appendMessage(msg, true));
+            this.ui.messageList.scrollTop = this.ui.messageList.scrollHeight - scrollHeight;
+            this.prevCursor = history.prevCursor;
+        } catch (err) {
+            console.error('加载历史消息失败:', err);
+        } finally {
+            this.loadingHistory = false;
+        }
+    }
+
     async leaveRoom(roomId, clearUI = true) {
         if (!roomId) return;
         
+        // 离开期间忽略该聊天室的帧，包括服务端因离开而取消订阅的通知
+        const isCurrent = this.currentRoom === roomId;
+        if (isCurrent) {
+            this.currentRoom = null;
+        }
+
         try {
             await Api.leaveRoom(roomId);
             
-            if (this.currentRoom === roomId) {
-                this.currentRoom = null;
-                this.ws.close();
+            if (isCurrent) {
+                this.ws.unsubscribe(roomId);
                 if (clearUI) {
                     this.ui.clearChatArea();
                 }
@@ -124,6 +265,9 @@ class Chat {
             
             await this.loadRoomList();
         } catch (err) {
+            if (isCurrent) {
+                this.currentRoom = roomId;
+            }
             console.error('离开聊天室失败:', err);
             alert(err.message || '离开聊天室失败');
         }
@@ -155,7 +299,7 @@ class Chat {
             if (this.currentRoom === roomId) {
                 this.ui.clearChatArea();
                 this.currentRoom = null;
-                this.ws.close();
+                this.ws.unsubscribe(roomId);
             }
             
             await this.loadRoomList();
@@ -169,8 +313,30 @@ class Chat {
         const content = input.value.trim();
         if (!content) return;
 
-        this.ws.sendTextMessage(content);
+        this.ws.sendTextMessage(content).catch((err) => {
+            this.showError(err.message);
+            // 发送失败时恢复未被改动的输入内容
+            if (!input.value) input.value = content;
+        });
         input.value = '';
+
+        // 服务端收到消息后会清除输入状态
+        clearTimeout(this.typingStopTimer);
+        this.typingSentAt = 0;
+    }
+
+    // 输入时每3秒发送一次开始输入，停止输入3秒后发送停止输入
+    handleTyping() {
+        const now = Date.now();
+        if (now - this.typingSentAt >= 3000) {
+            this.ws.sendTyping(true);
+            this.typingSentAt = now;
+        }
+        clearTimeout(this.typingStopTimer);
+        this.typingStopTimer = setTimeout(() => {
+            this.ws.sendTyping(false);
+            this.typingSentAt = 0;
+        }, 3000);
     }
 
     async handleImageUpload(e) {
@@ -178,11 +344,11 @@ class Chat {
         if (!file) return;
 
         try {
-            const base64 = await this.readFileAsBase64(file);
-            this.ws.sendImageMessage(base64);
+            const data = await Api.uploadAttachment(this.currentRoom, file);
+            await this.ws.sendImageMessage(data.attachment.id);
         } catch (err) {
             console.error('图片上传失败:', err);
-            alert('图片上传失败');
+            alert(err.message || '图片上传失败');
         }
 
         e.target.value = '';
@@ -193,33 +359,24 @@ class Chat {
         if (!file) return;
 
         try {
-            const base64 = await this.readFileAsBase64(file);
-            this.ws.sendFileMessage({
-                name: file.name,
-                size: file.size,
-                type: file.type,
-                data: base64
-            });
+            // 文件通过断点续传上传，完成后服务端自动发送到当前房间
+            await Api.uploadResumable(this.currentRoom, file);
         } catch (err) {
             console.error('文件上传失败:', err);
-            alert('文件上传失败');
+            alert(err.message || '文件上传失败');
         }
 
         e.target.value = '';
     }
 
-    readFileAsBase64(file) {
-        return new Promise((resolve, reject) => {
-            const reader = new FileReader();
-            reader.onload = () => resolve(reader.result);
-            reader.onerror = reject;
-            reader.readAsDataURL(file);
-        });
-    }
-
-    logout() {
-        localStorage.removeItem('token');
-        localStorage.removeItem('user');
+    async logout() {
+        try {
+            await Api.logout();
+        } catch (err) {
+            console.error('退出登录失败:', err);
+        }
+        this.ws.close();
+        Api.clearSession();
         window.location.href = '/html/login.html';
     }
 }
@@ -25,12 +25,18 @@ export const MessageType = {
 };
 
 /**
- * WebSocket消息类型常量定义
- * 与后端 consts.WsMsgType 对应
+ * WebSocket协议版本，每一帧的 v 字段
+ * 与后端 consts.WsProtocolVersion 对应
+ */
+export const WS_PROTOCOL_VERSION = 1;
+
+/**
+ * WebSocket事件类型常量定义，帧的 event 字段
+ * 与后端 consts.WsMsgType 对应；消息事件的 type 字段为 MessageType
  */
 export const WsMessageType = {
-    /** 文本消息 */
-    TEXT: 1,
+    /** 发送或收到消息 */
+    MESSAGE: 1,
     /** 用户加入房间 */
     JOIN: 2,
     /** 用户离开房间 */
@@ -40,7 +46,46 @@ export const WsMessageType = {
     /** 错误消息 */
     ERROR: 5,
     /** 系统通知 */
-    NOTIFICATION: 6
+    NOTIFICATION: 6,
+    /** 其他会话中收到的私信 */
+    DIRECT: 7,
+    /** 标记消息已读（客户端发送） */
+    READ: 8,
+    /** 已读回执 */
+    READ_RECEIPT: 9,
+    /** 消息已编辑 */
+    EDIT: 10,
+    /** 消息已撤回 */
+    DELETE: 11,
+    /** 添加表情回应（客户端发送） */
+    REACT: 12,
+    /** 取消表情回应（客户端发送） */
+    UNREACT: 13,
+    /** 表情回应变更 */
+    REACTION: 14,
+    /** 参与的话题有新回复 */
+    THREAD_REPLY: 15,
+    /** 话题回复数变更 */
+    THREAD_UPDATE: 16,
+    /** 开始或停止输入 */
+    TYPING: 17,
+    /** 订阅聊天室，服务端订阅成功后回传 */
+    SUBSCRIBE: 18,
+    /** 取消订阅聊天室，服务端回传；由服务端取消时附带原因 */
+    UNSUBSCRIBE: 19,
+    /** 带 requestId 的请求处理成功，发送消息时附带消息ID和序号 */
+    ACK: 20
+};
+
+/**
+ * 输入状态常量定义
+ * 与后端 consts.Typing 对应
+ */
+export const TypingState = {
+    /** 开始输入 */
+    START: 'start',
+    /** 停止输入 */
+    STOP: 'stop'
 };
 
 /**
@@ -51,6 +96,17 @@ export const Defaults = {
     AVATAR: '/resource/image/avatar/default.png'
 };
 
+/**
+ * 错误码常量定义
+ * 与后端 gcode 对应
+ */
+export const ErrorCode = {
+    /** 未授权（token 无效、过期或已吊销） */
+    NOT_AUTHORIZED: 61,
+    /** 超出用户或聊天室的存储配额 */
+    QUOTA_EXCEEDED: 1001
+};
+
 /**
  * 错误消息常量定义
  * 与后端错误消息对应
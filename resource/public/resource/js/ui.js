@@ -7,28 +7,77 @@ class ChatUI {
         this.roomList = document.getElementById('roomList');
         this.imageModal = new bootstrap.Modal(document.getElementById('imageModal'));
         this.createRoomModal = new bootstrap.Modal(document.getElementById('createRoomModal'));
+        this.attachmentUrls = new Map();
+        this.typingIndicator = document.getElementById('typingIndicator');
+        this.typingUsers = new Map();
     }
 
     // 消息渲染
-    appendMessage(message) {
+    deletedContent() {
+        return '<div class="content text-muted fst-italic">消息已撤回</div>';
+    }
+
+    updateMessageContent(messageId, content) {
+        const div = this.messageList.querySelector(`[data-message-id="${messageId}"] .content`);
+        if (div) {
+            div.innerHTML = `${this.escapeHtml(content)} <small class="text-muted edited">（已编辑）</small>`;
+        }
+    }
+
+    markMessageDeleted(messageId) {
+        const div = this.messageList.querySelector(`[data-message-id="${messageId}"] .content`);
+        if (div) {
+            div.outerHTML = this.deletedContent();
+        }
+    }
+
+    appendMessage(message, prepend = false) {
         const div = document.createElement('div');
         const isCurrentUser = window.currentUser && message.userId === window.currentUser.id;
         div.className = `message d-flex align-items-start ${isCurrentUser ? 'self' : ''}`;
         
+        if (message.id) {
+            div.dataset.messageId = message.id;
+        }
+
         const timestamp = new Date(message.timestamp).toLocaleTimeString();
+        const edited = message.editedAt ? ' <small class="text-muted edited">（已编辑）</small>' : '';
         let content = '';
 
-        switch (message.type) {
+        switch (message.deleted ? null : message.type) {
+            case null:
+                content = this.deletedContent();
+                break;
             case MessageType.TEXT:
-                content = `<div class="content">${this.escapeHtml(message.content)}</div>`;
+                content = `<div class="content">${this.escapeHtml(message.content)}${edited}</div>`;
                 break;
             case MessageType.IMAGE:
+                if (message.attachment) {
+                    // 按缩略图尺寸预留位置，图片加载后不再跳动
+                    const preview = this.previewImage(message.attachment);
+                    content = `
+                        <div class="content p-0">
+                            <img class="image-content" width="${preview.width}" height="${preview.height}" alt="${this.escapeHtml(message.attachment.name)}">
+                        </div>`;
+                    break;
+                }
+                // 旧消息的图片内联在内容中
                 content = `
                     <div class="content p-0">
                         <img src="${message.content}" class="image-content" onclick="chatUI.showImagePreview('${message.content}')">
                     </div>`;
                 break;
             case MessageType.FILE:
+                if (message.attachment) {
+                    content = `
+                        <div class="content file-content">
+                            <i class="fas fa-file me-2"></i>
+                            ${this.escapeHtml(message.attachment.name)}<br>
+                            <small class="text-muted">${this.formatFileSize(message.attachment.size)}</small>
+                        </div>`;
+                    break;
+                }
+                // 旧消息的文件内联在内容中
                 const fileInfo = JSON.parse(message.content);
                 content = `
                     <div class="content file-content" onclick="chatUI.downloadFile('${fileInfo.data}', '${fileInfo.name}')">
@@ -51,14 +100,71 @@ class ChatUI {
                 <div>
                     <div class="small text-muted mb-1">${message.nickname || message.username} - ${timestamp}</div>
                     ${content}
+                    <div class="reactions small mt-1"></div>
+                    <div class="thread small text-primary mt-1"></div>
                 </div>
             `;
+            if (message.id && !message.deleted) {
+                this.renderReactions(div, message.id, message.reactions || []);
+            }
+            if (message.attachment && !message.deleted) {
+                this.bindAttachment(div, message.attachment);
+            }
+            this.updateThread(message.id, message.replyCount || 0, div);
         }
         
+        // 加载更早的历史消息时插入到顶部，不滚动
+        if (prepend) {
+            this.messageList.insertBefore(div, this.messageList.firstChild);
+            return;
+        }
         this.messageList.appendChild(div);
         this.scrollToBottom();
     }
 
+    // 渲染表情回应，点击切换自己的回应
+    renderReactions(div, messageId, reactions) {
+        const container = div.querySelector('.reactions');
+        if (!container) return;
+
+        container.innerHTML = '';
+        reactions.concat(reactions.some(r => r.emoji === '👍') ? [] : [{ emoji: '👍', count: 0 }])
+            .forEach(reaction => {
+                const chip = document.createElement('span');
+                chip.className = `badge rounded-pill me-1 ${reaction.reacted ? 'bg-primary' : 'bg-light text-dark'}`;
+                chip.style.cursor = 'pointer';
+                chip.textContent = reaction.count > 0 ? `${reaction.emoji} ${reaction.count}` : `${reaction.emoji}+`;
+                chip.dataset.emoji = reaction.emoji;
+                chip.dataset.count = reaction.count;
+                chip.dataset.reacted = reaction.reacted ? '1' : '';
+                chip.onclick = () => window.chat.toggleReaction(messageId, reaction.emoji, chip.dataset.reacted === '1');
+                container.appendChild(chip);
+            });
+    }
+
+    // 根据实时回应变更更新计数
+    updateReaction(messageId, emoji, count, reacted) {
+        const div = this.messageList.querySelector(`[data-message-id="${messageId}"]`);
+        if (!div) return;
+
+        const reactions = Array.from(div.querySelectorAll('.reactions [data-emoji]'))
+            .map(chip => ({ emoji: chip.dataset.emoji, count: Number(chip.dataset.count), reacted: chip.dataset.reacted === '1' }))
+            .filter(r => r.count > 0 && r.emoji !== emoji);
+        if (count > 0) {
+            reactions.push({ emoji, count, reacted });
+        }
+        this.renderReactions(div, messageId, reactions);
+    }
+
+    // 更新话题回复数
+    updateThread(messageId, replyCount, div) {
+        div = div || this.messageList.querySelector(`[data-message-id="${messageId}"]`);
+        const container = div?.querySelector('.thread');
+        if (container) {
+            container.textContent = replyCount > 0 ? `${replyCount} 条回复` : '';
+        }
+    }
+
     // 更新用户列表
     updateUserList(users) {
         const header = this.userList.querySelector('.bg-light');
@@ -135,7 +241,37 @@ class ChatUI {
     }
 
     // 清空聊天区域
+    // 正在输入提示；服务端每3秒刷新一次，超过6秒未刷新视为已停止
+    updateTyping(userId, nickname, typing) {
+        const typingUser = this.typingUsers.get(userId);
+        if (typingUser) {
+            clearTimeout(typingUser.timer);
+        }
+        if (typing) {
+            const timer = setTimeout(() => this.updateTyping(userId, nickname, false), 6000);
+            this.typingUsers.set(userId, { nickname, timer });
+        } else {
+            this.typingUsers.delete(userId);
+        }
+
+        const names = [...this.typingUsers.values()].map(user => user.nickname);
+        if (names.length === 0) {
+            this.typingIndicator.textContent = '';
+        } else if (names.length > 3) {
+            this.typingIndicator.textContent = `${names.length} 人正在输入…`;
+        } else {
+            this.typingIndicator.textContent = `${names.join('、')} 正在输入…`;
+        }
+    }
+
+    clearTyping() {
+        this.typingUsers.forEach(user => clearTimeout(user.timer));
+        this.typingUsers.clear();
+        this.typingIndicator.textContent = '';
+    }
+
     clearChatArea() {
+        this.clearTyping();
         this.messageList.innerHTML = '';
         this.userList.innerHTML = `
             <div class="p-3 bg-light border-bottom">
@@ -155,10 +291,49 @@ class ChatUI {
         this.imageModal.show();
     }
 
+    // 附件需要携带令牌下载，内容转为对象URL后缓存
+    getAttachmentUrl(url) {
+        if (!this.attachmentUrls.has(url)) {
+            const objectUrl = Api.getAttachment(url).then(blob => URL.createObjectURL(blob));
+            objectUrl.catch(() => this.attachmentUrls.delete(url));
+            this.attachmentUrls.set(url, objectUrl);
+        }
+        return this.attachmentUrls.get(url);
+    }
+
+    // 消息中显示不小于480像素的最小缩略图，小图直接显示原图
+    previewImage(attachment) {
+        const thumbnails = attachment.thumbnails || [];
+        return thumbnails.find(t => t.size >= 480) || thumbnails[thumbnails.length - 1] || attachment;
+    }
+
+    // 图片附件加载缩略图，点击预览原图；文件附件点击时下载
+    bindAttachment(div, attachment) {
+        const image = div.querySelector('img.image-content');
+        if (image) {
+            this.getAttachmentUrl(this.previewImage(attachment).url)
+                .then(url => {
+                    image.src = url;
+                    image.onclick = () => this.getAttachmentUrl(attachment.url)
+                        .then(original => this.showImagePreview(original))
+                        .catch(err => console.error('加载图片失败:', err));
+                })
+                .catch(err => console.error('加载图片失败:', err));
+            return;
+        }
+
+        const file = div.querySelector('.file-content');
+        if (file) {
+            file.onclick = () => this.getAttachmentUrl(attachment.url)
+                .then(url => this.downloadFile(url, attachment.name))
+                .catch(err => alert(err.message || '下载失败'));
+        }
+    }
+
     // 下载文件
-    downloadFile(base64Data, fileName) {
+    downloadFile(href, fileName) {
         const link = document.createElement('a');
-        link.href = base64Data;
+        link.href = href;
         link.download = fileName;
         document.body.appendChild(link);
         link.click();
@@ -1,40 +1,103 @@
-import { MessageType } from './constants.js';
+import { MessageType, TypingState, WsMessageType, WS_PROTOCOL_VERSION } from './constants.js';
 
 class ChatWebSocket {
     constructor() {
         this.ws = null;
         this.currentRoom = null;
+        this.rooms = new Set();
+        this.lastSeq = new Map(); // 各聊天室收到的最后一条消息序号，重连后从其后补发
+        this.closed = false;
+        this.pendingRequests = new Map(); // 等待服务端确认的请求，以 requestId 为键
+        this.lastRequestId = 0;
         this.messageHandlers = new Map();
         this.reconnectAttempts = 0;
         this.maxReconnectAttempts = 5;
         this.reconnectDelay = 3000;
     }
 
-    connect(roomId) {
+    // 一个连接可订阅多个聊天室，每个帧通过 roomId 区分所属聊天室
+    connect() {
         if (this.ws) {
             this.ws.close();
         }
 
-        this.currentRoom = roomId;
+        this.closed = false;
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
         const token = Api.getToken();
-        const wsUrl = `${protocol}//${window.location.host}/ws/chat?roomId=${roomId}&token=${token}`;
+        const wsUrl = `${protocol}//${window.location.host}/ws/chat?token=${token}`;
 
         this.ws = new WebSocket(wsUrl);
         this.setupWebSocketHandlers();
     }
 
+    // lastSeq 为已加载的最后一条消息序号，服务端会先补发其后的消息
+    subscribe(roomId, lastSeq = 0) {
+        this.rooms.add(roomId);
+        this.lastSeq.set(roomId, lastSeq);
+        this.currentRoom = roomId;
+        // 尚未连接时在连接建立后订阅
+        if (this.ws && this.ws.readyState === WebSocket.OPEN) {
+            this.sendSubscribe(roomId);
+        }
+    }
+
+    sendSubscribe(roomId) {
+        this.send({
+            event: WsMessageType.SUBSCRIBE,
+            roomId,
+            seq: this.lastSeq.get(roomId) || 0
+        });
+    }
+
+    unsubscribe(roomId) {
+        if (this.currentRoom === roomId) {
+            this.currentRoom = null;
+        }
+        this.lastSeq.delete(roomId);
+        if (!this.rooms.delete(roomId)) {
+            return;
+        }
+        this.send({
+            event: WsMessageType.UNSUBSCRIBE,
+            roomId
+        });
+    }
+
     setupWebSocketHandlers() {
+        this.ws.onopen = () => {
+            // 重连后重新订阅之前的聊天室，并补发断线期间错过的消息
+            this.rooms.forEach(roomId => this.sendSubscribe(roomId));
+            // 断线时未确认的消息以原 nonce 重发，已保存的不会重复发送
+            this.pendingRequests.forEach((request, requestId) => this.send({ ...request.message, requestId }));
+        };
+
         this.ws.onmessage = (event) => {
             const message = JSON.parse(event.data);
-            const handler = this.messageHandlers.get(message.type);
+            this.settleRequest(message);
+            // 服务端取消的订阅（如被移出聊天室）不再重新订阅
+            if (message.event === WsMessageType.UNSUBSCRIBE) {
+                this.rooms.delete(message.roomId);
+                this.lastSeq.delete(message.roomId);
+            }
+            if (message.seq && this.rooms.has(message.roomId)) {
+                this.lastSeq.set(message.roomId, Math.max(this.lastSeq.get(message.roomId) || 0, message.seq));
+            }
+            const handler = this.messageHandlers.get(message.event);
             if (handler) {
                 handler(message);
             }
         };
 
-        this.ws.onclose = () => {
+        this.ws.onclose = (event) => {
             console.log('WebSocket连接已关闭');
+            // 服务端因权限原因关闭连接（如会话已被撤销）时不再重连
+            if (this.closed || event.code === 1008) {
+                this.rejectPendingRequests();
+                this.currentRoom = null;
+                this.rooms.clear();
+                return;
+            }
+            this.rejectPendingRequests(request => !request.message.nonce);
             this.handleReconnect();
         };
 
@@ -45,14 +108,17 @@ class ChatWebSocket {
 
     handleReconnect() {
         if (this.reconnectAttempts >= this.maxReconnectAttempts) {
+            this.rejectPendingRequests();
             this.emit('reconnectFailed');
             return;
         }
 
-        if (this.currentRoom) {
-            setTimeout(() => {
+        if (!this.closed) {
+            setTimeout(async () => {
                 console.log(`尝试重新连接WebSocket... (${this.reconnectAttempts + 1}/${this.maxReconnectAttempts})`);
-                this.connect(this.currentRoom);
+                // 访问令牌可能已过期，重连前先刷新
+                await Api.refreshToken();
+                this.connect();
                 this.reconnectAttempts++;
             }, this.reconnectDelay);
         }
@@ -99,44 +165,112 @@ class ChatWebSocket {
 
     send(message) {
         if (this.ws && this.ws.readyState === WebSocket.OPEN) {
-            this.ws.send(JSON.stringify(message));
+            this.ws.send(JSON.stringify({ v: WS_PROTOCOL_VERSION, ...message }));
+            return true;
+        }
+        console.error('WebSocket未连接');
+        return false;
+    }
+
+    // 发送带 requestId 的请求，服务端确认后返回确认帧，失败时以错误内容拒绝。
+    // 带 nonce 的消息在连接断开时保留，重连后重发
+    request(message) {
+        const requestId = String(++this.lastRequestId);
+        return new Promise((resolve, reject) => {
+            if (!this.send({ ...message, requestId }) && (!message.nonce || this.closed)) {
+                reject(new Error('WebSocket未连接'));
+                return;
+            }
+            this.pendingRequests.set(requestId, { message, resolve, reject });
+        });
+    }
+
+    settleRequest(message) {
+        const request = message.requestId && this.pendingRequests.get(message.requestId);
+        if (!request) return;
+        this.pendingRequests.delete(message.requestId);
+        if (message.event === WsMessageType.ACK) {
+            request.resolve(message);
         } else {
-            console.error('WebSocket未连接');
+            request.reject(new Error(message.content));
         }
     }
 
+    // 连接断开时未确认的请求结果未知，按失败处理
+    rejectPendingRequests(filter = () => true) {
+        this.pendingRequests.forEach((request, requestId) => {
+            if (!filter(request)) return;
+            this.pendingRequests.delete(requestId);
+            request.reject(new Error('WebSocket连接已断开'));
+        });
+    }
+
+    // 消息附带唯一的 nonce，服务端据此识别重发的消息并返回原消息
+    sendChatMessage(type, fields) {
+        return this.request({
+            event: WsMessageType.MESSAGE,
+            type,
+            ...fields,
+            roomId: this.currentRoom,
+            nonce: createNonce()
+        });
+    }
+
     sendTextMessage(content) {
+        return this.sendChatMessage(MessageType.TEXT, { content });
+    }
+
+    sendImageMessage(attachmentId) {
+        return this.sendChatMessage(MessageType.IMAGE, { attachmentId });
+    }
+
+    sendFileMessage(attachmentId) {
+        return this.sendChatMessage(MessageType.FILE, { attachmentId });
+    }
+
+    markRead(messageId) {
         this.send({
-            type: MessageType.TEXT,
-            content,
+            event: WsMessageType.READ,
+            id: messageId,
             roomId: this.currentRoom
         });
     }
 
-    sendImageMessage(base64Data) {
+    react(messageId, emoji, remove = false) {
         this.send({
-            type: MessageType.IMAGE,
-            content: base64Data,
+            event: remove ? WsMessageType.UNREACT : WsMessageType.REACT,
+            id: messageId,
+            content: emoji,
             roomId: this.currentRoom
         });
     }
 
-    sendFileMessage(fileInfo) {
+    sendTyping(typing) {
         this.send({
-            type: MessageType.FILE,
-            content: JSON.stringify(fileInfo),
+            event: WsMessageType.TYPING,
+            content: typing ? TypingState.START : TypingState.STOP,
             roomId: this.currentRoom
         });
     }
 
     close() {
+        this.closed = true;
+        this.currentRoom = null;
+        this.rooms.clear();
         if (this.ws) {
             this.ws.close();
             this.ws = null;
-            this.currentRoom = null;
         }
     }
 }
 
+function createNonce() {
+    if (window.crypto?.randomUUID) {
+        return window.crypto.randomUUID();
+    }
+    // 非安全上下文（如 HTTP 访问）中没有 randomUUID
+    return `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`;
+}
+
 // 导出WebSocket类
 window.ChatWebSocket = ChatWebSocket;
\ No newline at end of file
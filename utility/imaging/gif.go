@@ -0,0 +1,64 @@
+package imaging
+
+import (
+	"encoding/binary"
+)
+
+// maxGIFFrames is the maximum number of frames of an animation
+const maxGIFFrames = 1000
+
+// gifFrames counts the frames of a GIF file and their total number of pixels by walking its
+// blocks without decoding them, so that animations too large to decode can be rejected first.
+// It reports false if the file is malformed.
+func gifFrames(data []byte) (frames, pixels int, ok bool) {
+	// Header and logical screen descriptor, followed by the global color table
+	if len(data) < 13 {
+		return 0, 0, false
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: label, then data sub-blocks
+			if pos += 2; pos > len(data) {
+				return 0, 0, false
+			}
+		case 0x2C: // Image descriptor, local color table and LZW minimum code size
+			if pos+10 > len(data) {
+				return 0, 0, false
+			}
+			width := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			frames++
+			pixels += width * height
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			if pos++; pos > len(data) {
+				return 0, 0, false
+			}
+		case 0x3B: // Trailer
+			return frames, pixels, true
+		default:
+			return 0, 0, false
+		}
+
+		// Skip the data sub-blocks up to the block terminator
+		for {
+			if pos >= len(data) {
+				return 0, 0, false
+			}
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// MIME types of the image formats that can be processed
+const (
+	MimeJPEG = "image/jpeg"
+	MimePNG  = "image/png"
+	MimeGIF  = "image/gif"
+)
+
+const (
+	jpegQuality      = 90
+	thumbnailQuality = 85
+)
+
+var (
+	// ErrUnsupported is returned for data that is not a valid image of a supported format
+	ErrUnsupported = errors.New("unsupported image")
+	// ErrTooLarge is returned for images with more pixels than allowed
+	ErrTooLarge = errors.New("image is too large")
+)
+
+// Image is an uploaded image re-encoded without metadata
+type Image struct {
+	Data       []byte
+	MimeType   string
+	Width      int
+	Height     int
+	Thumbnails []Thumbnail
+}
+
+// Thumbnail is a scaled down copy of an image fitting a square bounding box
+type Thumbnail struct {
+	Size     int // Edge of the bounding box in pixels
+	Width    int
+	Height   int
+	Data     []byte
+	MimeType string
+}
+
+// Process validates an image by decoding it, re-encodes it to drop EXIF and other metadata and
+// generates a thumbnail for every bounding box size smaller than the image. Images with more than
+// maxPixels pixels, and animations whose frames have more than maxPixels pixels in total, are
+// rejected before decoding.
+func Process(data []byte, sizes []int, maxPixels int) (*Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupported
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var (
+		result    = &Image{}
+		frame     image.Image
+		animation *gif.GIF
+		buf       bytes.Buffer
+	)
+	switch format {
+	case "jpeg":
+		frame, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupported
+		}
+		// The orientation is lost with the metadata, so it is applied to the pixels
+		frame = orient(frame, jpegOrientation(data))
+		err = jpeg.Encode(&buf, frame, &jpeg.Options{Quality: jpegQuality})
+		result.MimeType = MimeJPEG
+	case "png":
+		frame, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupported
+		}
+		err = png.Encode(&buf, frame)
+		result.MimeType = MimePNG
+	case "gif":
+		// Every frame is decoded, so the whole animation must fit the pixel budget
+		frames, pixels, ok := gifFrames(data)
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		if frames > maxGIFFrames || pixels > maxPixels {
+			return nil, ErrTooLarge
+		}
+		// Animations are kept; comments and application extensions are dropped
+		animation, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(animation.Image) == 0 {
+			return nil, ErrUnsupported
+		}
+		// The first frame may not cover the whole logical screen
+		frame = animation.Image[0]
+		if frame.Bounds() != image.Rect(0, 0, config.Width, config.Height) {
+			canvas := image.NewNRGBA(image.Rect(0, 0, config.Width, config.Height))
+			draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+			frame = canvas
+		}
+		err = gif.EncodeAll(&buf, animation)
+		result.MimeType = MimeGIF
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := frame.Bounds()
+	result.Data = buf.Bytes()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+	for _, size := range sizes {
+		if size <= 0 || (result.Width <= size && result.Height <= size) {
+			continue
+		}
+		thumbnail, err := makeThumbnail(frame, size, result.MimeType == MimeJPEG)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnails = append(result.Thumbnails, *thumbnail)
+	}
+	return result, nil
+}
+
+// makeThumbnail scales an image to fit a square bounding box, keeping its aspect ratio.
+// Photos are encoded as JPEG; other images as PNG to keep transparency.
+func makeThumbnail(img image.Image, size int, photo bool) (*Thumbnail, error) {
+	bounds := img.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, bounds.Dy()*size/bounds.Dx())
+	} else {
+		width = max(1, bounds.Dx()*size/bounds.Dy())
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+	thumbnail := &Thumbnail{Size: size, Width: width, Height: height}
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	if photo {
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: thumbnailQuality})
+		thumbnail.MimeType = MimeJPEG
+	} else {
+		err = png.Encode(&buf, scaled)
+		thumbnail.MimeType = MimePNG
+	}
+	if err != nil {
+		return nil, err
+	}
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
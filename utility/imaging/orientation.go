@@ -0,0 +1,113 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// exifOrientationTag is the TIFF tag holding the orientation of a photo
+const exifOrientationTag = 0x0112
+
+// jpegOrientation returns the EXIF orientation of a JPEG file, 1 if it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		// Metadata segments precede the start of scan
+		if marker == 0xDA || length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of TIFF-structured EXIF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// orient transforms an image so it displays upright without its EXIF orientation
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		// Orientations 5 to 8 swap width and height
+		dstWidth, dstHeight = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Config holds the argon2id cost parameters
+type Argon2Config struct {
+	Time       uint32 `json:"time"`       // Number of iterations
+	Memory     uint32 `json:"memory"`     // Memory in KiB
+	Threads    uint8  `json:"threads"`    // Degree of parallelism
+	KeyLength  uint32 `json:"keyLength"`  // Length of the derived key in bytes
+	SaltLength uint32 `json:"saltLength"` // Length of the random salt in bytes
+}
+
+// DefaultArgon2Config returns the recommended argon2id parameters
+func DefaultArgon2Config() Argon2Config {
+	return Argon2Config{
+		Time:       1,
+		Memory:     64 * 1024,
+		Threads:    4,
+		KeyLength:  32,
+		SaltLength: 16,
+	}
+}
+
+// Argon2idHasher hashes passwords with argon2id
+type Argon2idHasher struct {
+	cfg Argon2Config
+}
+
+// NewArgon2idHasher creates a new Argon2idHasher, filling in missing parameters with defaults
+func NewArgon2idHasher(cfg Argon2Config) *Argon2idHasher {
+	def := DefaultArgon2Config()
+	if cfg.Time == 0 {
+		cfg.Time = def.Time
+	}
+	if cfg.Memory == 0 {
+		cfg.Memory = def.Memory
+	}
+	if cfg.Threads == 0 {
+		cfg.Threads = def.Threads
+	}
+	if cfg.KeyLength == 0 {
+		cfg.KeyLength = def.KeyLength
+	}
+	if cfg.SaltLength == 0 {
+		cfg.SaltLength = def.SaltLength
+	}
+	return &Argon2idHasher{cfg: cfg}
+}
+
+// Hash returns the argon2id hash of a password in PHC string format
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.cfg.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Time, h.cfg.Memory, h.cfg.Threads, h.cfg.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.cfg.Memory, h.cfg.Time, h.cfg.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks a password against an argon2id hash
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Matches reports whether the encoded hash is an argon2id hash
+func (h *Argon2idHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash reports whether the argon2id hash uses different parameters
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Time != h.cfg.Time ||
+		params.Memory != h.cfg.Memory ||
+		params.Threads != h.cfg.Threads ||
+		uint32(len(key)) != h.cfg.KeyLength ||
+		uint32(len(salt)) != h.cfg.SaltLength
+}
+
+// decodeArgon2id parses an argon2id PHC string into its parameters, salt and key
+func decodeArgon2id(encoded string) (params Argon2Config, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, err
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,54 @@
+package password
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is the bcrypt cost used when none is configured
+const DefaultBcryptCost = 12
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new BcryptHasher with the given cost
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash returns the bcrypt hash of a password
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify checks a password against a bcrypt hash
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Matches reports whether the encoded hash is a bcrypt hash
+func (h *BcryptHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash reports whether the bcrypt hash uses a different cost
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
@@ -0,0 +1,43 @@
+package password
+
+import (
+	"crypto/subtle"
+	"regexp"
+
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// legacyMd5Pattern matches the unsalted hex MD5 hashes stored by earlier versions
+var legacyMd5Pattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// errLegacyHash is returned when trying to create a new legacy MD5 hash
+var errLegacyHash = gerror.New("MD5 password hashing is no longer supported")
+
+// legacyMd5Hasher verifies legacy unsalted MD5 hashes so they can be upgraded on login.
+// It must never be used to hash new passwords.
+type legacyMd5Hasher struct{}
+
+// Hash is not supported for legacy MD5 hashes
+func (legacyMd5Hasher) Hash(password string) (string, error) {
+	return "", errLegacyHash
+}
+
+// Verify checks a password against a legacy MD5 hash
+func (legacyMd5Hasher) Verify(encoded, password string) (bool, error) {
+	hash, err := gmd5.EncryptString(password)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1, nil
+}
+
+// Matches reports whether the encoded hash is a legacy MD5 hash
+func (legacyMd5Hasher) Matches(encoded string) bool {
+	return legacyMd5Pattern.MatchString(encoded)
+}
+
+// NeedsRehash always reports true since legacy MD5 hashes must be upgraded
+func (legacyMd5Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
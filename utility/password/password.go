@@ -0,0 +1,103 @@
+package password
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gvalid"
+)
+
+// Supported password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// MaxLength is the maximum length of a password in bytes, the input limit of bcrypt
+const MaxLength = 72
+
+// RuleMaxLength is the validation rule rejecting passwords longer than MaxLength bytes.
+// Length rules count characters, which may take several bytes each.
+const RuleMaxLength = "password-max-bytes"
+
+func init() {
+	gvalid.RegisterRule(RuleMaxLength, func(ctx context.Context, in gvalid.RuleFuncInput) error {
+		if len(in.Value.String()) > MaxLength {
+			return gerror.Newf("Password must be at most %d bytes", MaxLength)
+		}
+		return nil
+	})
+}
+
+// PasswordHasher hashes and verifies passwords
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a plain text password
+	Hash(password string) (string, error)
+	// Verify checks a plain text password against an encoded hash
+	Verify(encoded, password string) (bool, error)
+	// Matches reports whether an encoded hash was produced by this algorithm
+	Matches(encoded string) bool
+	// NeedsRehash reports whether an encoded hash should be replaced by a fresh one
+	NeedsRehash(encoded string) bool
+}
+
+// Config holds the password hashing configuration
+type Config struct {
+	Algorithm  string       `json:"algorithm"`
+	BcryptCost int          `json:"bcryptCost"`
+	Argon2     Argon2Config `json:"argon2"`
+}
+
+// DefaultConfig returns the configuration used when config.yaml has no password section
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:  AlgorithmBcrypt,
+		BcryptCost: DefaultBcryptCost,
+		Argon2:     DefaultArgon2Config(),
+	}
+}
+
+// New creates the hasher selected by the configuration
+func New(cfg Config) PasswordHasher {
+	if cfg.Algorithm == AlgorithmArgon2id {
+		return NewArgon2idHasher(cfg.Argon2)
+	}
+	return NewBcryptHasher(cfg.BcryptCost)
+}
+
+// NewFromConfig creates the hasher configured in the "password" section of config.yaml
+func NewFromConfig(ctx context.Context) PasswordHasher {
+	cfg := DefaultConfig()
+	if err := g.Cfg().MustGet(ctx, "password").Scan(&cfg); err != nil {
+		g.Log().Warning(ctx, "Invalid password config, using defaults:", err)
+		cfg = DefaultConfig()
+	}
+	return New(cfg)
+}
+
+// Verify checks a password against an encoded hash of any supported format,
+// including legacy unsalted MD5 hashes. The configured hasher is tried first.
+func Verify(hasher PasswordHasher, encoded, password string) (bool, error) {
+	candidates := []PasswordHasher{
+		hasher,
+		NewBcryptHasher(DefaultBcryptCost),
+		NewArgon2idHasher(DefaultArgon2Config()),
+		legacyMd5Hasher{},
+	}
+	for _, h := range candidates {
+		if h.Matches(encoded) {
+			return h.Verify(encoded, password)
+		}
+	}
+	return false, nil
+}
+
+// NeedsRehash reports whether an encoded hash was produced by another algorithm
+// or with outdated parameters and should be upgraded by the configured hasher
+func NeedsRehash(hasher PasswordHasher, encoded string) bool {
+	if !hasher.Matches(encoded) {
+		return true
+	}
+	return hasher.NeedsRehash(encoded)
+}
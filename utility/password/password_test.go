@@ -0,0 +1,138 @@
+package password
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/frame/g"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Config keeps argon2id fast enough for tests
+var testArgon2Config = Argon2Config{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16}
+
+func TestRoundTrip(t *testing.T) {
+	for name, hasher := range map[string]PasswordHasher{
+		AlgorithmBcrypt:   NewBcryptHasher(bcrypt.MinCost),
+		AlgorithmArgon2id: NewArgon2idHasher(testArgon2Config),
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := hasher.Hash("secret123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasher.Matches(encoded) {
+				t.Errorf("hasher does not match its own hash %q", encoded)
+			}
+			if ok, err := Verify(hasher, encoded, "secret123"); !ok || err != nil {
+				t.Errorf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := Verify(hasher, encoded, "secret124"); ok || err != nil {
+				t.Errorf("Verify(wrong) = %v, %v", ok, err)
+			}
+			if NeedsRehash(hasher, encoded) {
+				t.Error("fresh hash needs rehash")
+			}
+
+			// Hashes are salted
+			other, _ := hasher.Hash("secret123")
+			if other == encoded {
+				t.Error("hashing twice gave the same hash")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	encoded, err := NewBcryptHasher(bcrypt.MinCost).Hash("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(NewBcryptHasher(bcrypt.MinCost+1), encoded) {
+		t.Error("bcrypt hash with an outdated cost does not need rehash")
+	}
+
+	argon2Hasher := NewArgon2idHasher(testArgon2Config)
+	if !NeedsRehash(argon2Hasher, encoded) {
+		t.Error("bcrypt hash does not need rehash when argon2id is configured")
+	}
+	// Hashes of another algorithm still verify until they are upgraded
+	if ok, err := Verify(argon2Hasher, encoded, "secret123"); !ok || err != nil {
+		t.Errorf("Verify(bcrypt hash with argon2id configured) = %v, %v", ok, err)
+	}
+
+	encoded, err = argon2Hasher.Hash("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := testArgon2Config
+	changed.Time++
+	if !NeedsRehash(NewArgon2idHasher(changed), encoded) {
+		t.Error("argon2id hash with outdated parameters does not need rehash")
+	}
+}
+
+func TestLegacyMd5Upgrade(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+	legacy, err := gmd5.EncryptString("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := Verify(hasher, legacy, "secret124"); ok || err != nil {
+		t.Errorf("Verify(wrong, legacy) = %v, %v", ok, err)
+	}
+	if ok, err := Verify(hasher, legacy, "secret123"); !ok || err != nil {
+		t.Fatalf("Verify(correct, legacy) = %v, %v", ok, err)
+	}
+	if !NeedsRehash(hasher, legacy) {
+		t.Fatal("legacy MD5 hash does not need rehash")
+	}
+
+	// After a successful login the hash is replaced by one of the configured hasher
+	upgraded, err := hasher.Hash("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NeedsRehash(hasher, upgraded) {
+		t.Error("upgraded hash needs rehash")
+	}
+	if ok, err := Verify(hasher, upgraded, "secret123"); !ok || err != nil {
+		t.Errorf("Verify(correct, upgraded) = %v, %v", ok, err)
+	}
+
+	if _, err := (legacyMd5Hasher{}).Hash("secret123"); err == nil {
+		t.Error("new legacy MD5 hashes can be created")
+	}
+}
+
+func TestVerifyUnknownFormat(t *testing.T) {
+	if ok, err := Verify(NewBcryptHasher(bcrypt.MinCost), "plaintext", "plaintext"); ok || err != nil {
+		t.Errorf("Verify(unknown format) = %v, %v", ok, err)
+	}
+}
+
+func TestMaxLengthRule(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		password string
+		valid    bool
+	}{
+		{strings.Repeat("a", MaxLength), true},
+		{strings.Repeat("a", MaxLength+1), false},
+		// 25 characters, but 75 bytes
+		{strings.Repeat("密", 25), false},
+	} {
+		err := g.Validator().Rules(RuleMaxLength).Data(tt.password).Run(ctx)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("password of %d bytes: valid = %v, want %v (%v)", len(tt.password), valid, tt.valid, err)
+		}
+	}
+
+	// Passwords within the limit can be hashed by bcrypt
+	if _, err := NewBcryptHasher(bcrypt.MinCost).Hash(strings.Repeat("a", MaxLength)); err != nil {
+		t.Errorf("hashing a password of MaxLength bytes failed: %v", err)
+	}
+}